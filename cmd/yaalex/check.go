@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+)
+
+func checkCommand(args []string, std stdio) int {
+	flags := newFlagSet("check", "<spec.lex>", "Validates the definition, and warns about rules the lexer never recognizes.\nPrints \"ok\" if there is nothing to report.", std)
+	constructionFlag := flags.String("construction", "direct", "DFA construction: direct or thompson")
+	strictFlag := flags.Bool("strict", false, "Fail if there are warnings")
+	arguments, code, ok := flags.parse(args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	lexer, err := buildSpec(arguments[0], *constructionFlag, std)
+	if err != nil {
+		fmt.Fprintf(std.err, "%s: error: %s\n", displayName(arguments[0]), err)
+		return EXIT_FAILURE
+	}
+
+	warnings := diagnostics(lexer)
+	for _, warning := range warnings {
+		fmt.Fprintf(std.err, "%s: warning: %s\n", displayName(arguments[0]), warning)
+	}
+	if len(warnings) > 0 {
+		if *strictFlag {
+			return EXIT_FAILURE
+		}
+		return EXIT_OK
+	}
+	fmt.Fprintln(std.out, "ok")
+	return EXIT_OK
+}
+
+// Returns the warnings of a lexer that builds.
+func diagnostics(lexer *generator.Lexer) []string {
+	warnings := make([]string, 0)
+	if len(lexer.Definition.Rules) == 0 {
+		return append(warnings, "there are no rules, the lexer recognizes nothing")
+	}
+
+	// A rule is recognized if some state ends its lexemes
+	recognized := make([]bool, len(lexer.Definition.Rules))
+	for _, state := range lexer.Automata.States {
+		if state.IsFinal && state.Rule >= 0 {
+			recognized[state.Rule] = true
+		}
+	}
+	for priority, rule := range lexer.Definition.Rules {
+		if !recognized[priority] {
+			warnings = append(warnings, fmt.Sprintf("rule %d %s is never recognized, the rules before it match all its lexemes", priority+1, rule.Pattern))
+		}
+		if action := lexer.Automata.Rules[priority]; action.TrailingContext && action.TrailingLength < 0 {
+			warnings = append(warnings, fmt.Sprintf("rule %d %s has trailing context of variable length, its lexemes may include part of it where it overlaps the pattern before \"/\"", priority+1, rule.Pattern))
+		}
+	}
+	return warnings
+}
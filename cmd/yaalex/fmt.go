@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+	yalex_reader "github.com/DanielRasho/Lexer/internal/Generator/YALexReader"
+)
+
+func fmtCommand(args []string, std stdio) int {
+	flags := newFlagSet("fmt", "<spec.lex>...", "Reformats the definitions, writing them to stdout: aligns the columns of the named\npatterns and the rules, writes named patterns as \"NAME pattern\", and formats the\nheader, the footer and the actions with gofmt. Comments are kept.", std)
+	writeFlag := flags.Bool("w", false, "Write the result to the definitions instead of stdout")
+	listFlag := flags.Bool("l", false, "List the definitions that are not formatted instead of writing them")
+	targetFlag := flags.String("target", string(Lex_writer.GO_TARGET), "Language of the code of the definitions: go, c or typescript. Only Go code is formatted")
+	arguments, code, ok := flags.parse(args, 1, -1)
+	if !ok {
+		return code
+	}
+	target, err := Lex_writer.ParseTarget(*targetFlag)
+	if err != nil {
+		return fail(std, "fmt", err)
+	}
+	options := yalex_reader.FormatOptions{GoCode: target == Lex_writer.GO_TARGET}
+
+	code = EXIT_OK
+	for _, path := range arguments {
+		if (*writeFlag || *listFlag) && path == "-" {
+			return fail(std, "fmt", errors.New("-w and -l need files, not stdin"))
+		}
+		source, err := openInput(path, std)
+		if err != nil {
+			code = fail(std, "fmt", err)
+			continue
+		}
+		var original, formatted bytes.Buffer
+		err = yalex_reader.Format(io.TeeReader(source, &original), &formatted, options)
+		source.Close()
+		if err != nil {
+			code = fail(std, "fmt", fmt.Errorf("%s: %w", displayName(path), err))
+			continue
+		}
+
+		changed := !bytes.Equal(original.Bytes(), formatted.Bytes())
+		switch {
+		case *listFlag:
+			if changed {
+				fmt.Fprintln(std.out, path)
+			}
+		case *writeFlag:
+			if changed {
+				err = os.WriteFile(path, formatted.Bytes(), 0o644)
+			}
+		default:
+			_, err = std.out.Write(formatted.Bytes())
+		}
+		if err != nil {
+			code = fail(std, "fmt", err)
+		}
+	}
+	return code
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+)
+
+func generateCommand(args []string, std stdio) int {
+	flags := newFlagSet("generate", "<spec.lex>", "Writes the lexer of the definition, in Go by default.", std)
+	outputFlag := flags.String("o", "-", "Output file path")
+	targetFlag := flags.String("target", string(Lex_writer.GO_TARGET), "Language of the lexer: go, c or typescript")
+	packageFlag := flags.String("package", "main", "Package of the lexer, for the go target")
+	constructionFlag := flags.String("construction", string(dfa.DIRECT_CONSTRUCTION), "DFA construction: direct or thompson")
+	emitASTFlag := flags.String("emit-ast", "", "Write the syntax tree diagram to this path: .dot, .mmd (Mermaid), .svg or .png (needs Graphviz)")
+	emitDFAFlag := flags.String("emit-dfa", "", "Write the DFA diagram to this path: .dot, .mmd (Mermaid), .svg or .png (needs Graphviz)")
+	reportFlag := flags.String("report", "", "Write an HTML report of the rules, the DFA and a simulator of the lexer to this path")
+	automatonFlag := flags.String("emit-automaton", "", "Write the DFA to this path, to load it without generating Go code: .json or any other extension for binary")
+	invalidUTF8Flag := flags.String("invalid-utf8", string(generator.INVALID_UTF8_ERROR), "How the lexer handles invalid UTF-8: error (INVALID_UTF8 tokens) or replace (read as U+FFFD)")
+	verboseFlag := flags.Bool("v", false, "Log how long each phase takes and its details")
+	quietFlag := flags.Bool("q", false, "Log only errors, not warnings")
+	dumpFlag := flags.String("dump", "", "Write these intermediate artifacts as JSON, separated by commas: postfix, positions, states")
+	dumpDirFlag := flags.String("dump-dir", "", "Directory to write the -dump artifacts to, the one of the output file by default")
+	arguments, code, ok := flags.parse(args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	construction, err := dfa.ParseConstruction(*constructionFlag)
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+	target, err := Lex_writer.ParseTarget(*targetFlag)
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+	invalidUTF8, err := generator.ParseInvalidUTF8Policy(*invalidUTF8Flag)
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+	dump, err := generator.ParseDumpArtifacts(*dumpFlag)
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+
+	level := slog.LevelWarn
+	if *verboseFlag {
+		level = slog.LevelDebug
+	} else if *quietFlag {
+		level = slog.LevelError
+	}
+	logger := slog.New(slog.NewTextHandler(std.err, &slog.HandlerOptions{Level: level}))
+
+	// The stamp of the lexer has the path of the definition relative to it
+	dumpDir := *dumpDirFlag
+	specPath := ""
+	if *outputFlag != "-" {
+		if dumpDir == "" {
+			dumpDir = filepath.Dir(*outputFlag)
+		}
+		if arguments[0] != "-" {
+			specPath = generator.RelativeSpecPath(arguments[0], *outputFlag)
+		}
+	}
+
+	source, err := openInput(arguments[0], std)
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+	defer source.Close()
+
+	// The output is only written if the whole generation succeeds
+	var output bytes.Buffer
+	err = generator.Generate(source, displayName(arguments[0]), &output, generator.Options{
+		Construction: construction,
+		Target:       target,
+		Package:      *packageFlag,
+		InvalidUTF8:  invalidUTF8,
+		Logger:       logger,
+		EmitAST:      *emitASTFlag,
+		EmitDFA:      *emitDFAFlag,
+		Report:       *reportFlag,
+		Automaton:    *automatonFlag,
+		Dump:         dump,
+		DumpDir:      dumpDir,
+		SpecPath:     specPath,
+	})
+	if err != nil {
+		return fail(std, "generate", err)
+	}
+	if err := writeOutput(*outputFlag, output.Bytes(), std); err != nil {
+		return fail(std, "generate", err)
+	}
+	return EXIT_OK
+}
+
+// Builds the lexer of the definition at path in memory, for the commands that don't write it.
+func buildSpec(path string, construction string, std stdio) (*generator.Lexer, error) {
+	parsedConstruction, err := dfa.ParseConstruction(construction)
+	if err != nil {
+		return nil, err
+	}
+	source, err := openInput(path, std)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	return generator.Build(source, displayName(path), generator.Options{Construction: parsedConstruction})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+)
+
+func graphCommand(args []string, std stdio) int {
+	flags := newFlagSet("graph", "<spec.lex>", "Draws the DFA of the definition, or its syntax tree with -ast.", std)
+	outputFlag := flags.String("o", "-", "Output file path")
+	formatFlag := flags.String("format", "", "Format of the diagram: dot, mermaid, svg or png (needs Graphviz and -o).\nBy default, the one of the extension of -o, or dot")
+	astFlag := flags.Bool("ast", false, "Draw the syntax tree of the rules instead of the DFA")
+	constructionFlag := flags.String("construction", string(dfa.DIRECT_CONSTRUCTION), "DFA construction: direct or thompson")
+	arguments, code, ok := flags.parse(args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	format := dfa.DOT_DIAGRAM
+	var err error
+	if *formatFlag != "" {
+		format, err = dfa.ParseDiagramFormat(*formatFlag)
+	} else if *outputFlag != "-" {
+		format, err = dfa.DiagramFormatOf(*outputFlag)
+	}
+	if err != nil {
+		return fail(std, "graph", err)
+	}
+
+	lexer, err := buildSpec(arguments[0], *constructionFlag, std)
+	if err != nil {
+		return fail(std, "graph", err)
+	}
+
+	// Images are rendered by Graphviz to a file
+	if format == dfa.PNG_DIAGRAM {
+		if outputFormat, _ := dfa.DiagramFormatOf(*outputFlag); outputFormat != dfa.PNG_DIAGRAM {
+			return fail(std, "graph", errors.New("png diagrams need a -o path ending in .png"))
+		}
+		if *astFlag {
+			err = dfa.WriteASTDiagram(lexer.Expresion, *outputFlag)
+		} else {
+			err = dfa.WriteDFADiagram(lexer.Automata, *outputFlag)
+		}
+		if err != nil {
+			return fail(std, "graph", err)
+		}
+		return EXIT_OK
+	}
+
+	var diagram string
+	if *astFlag {
+		diagram, err = dfa.ASTDiagram(lexer.Expresion, format)
+	} else {
+		diagram, err = dfa.DFADiagram(lexer.Automata, format)
+	}
+	if err != nil {
+		return fail(std, "graph", err)
+	}
+	if err := writeOutput(*outputFlag, []byte(diagram), std); err != nil {
+		return fail(std, "graph", err)
+	}
+	return EXIT_OK
+}
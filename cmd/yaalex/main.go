@@ -0,0 +1,179 @@
+// yaalex generates lexers from YALex definitions. Its commands also check, run, draw
+// and format definitions:
+//
+//	yaalex <command> [flags] [arguments]
+//
+// Files can be "-" to read from stdin or write to stdout. It exits with 0 on success,
+// 1 if the command fails and 2 if it is misused.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	EXIT_OK      = 0
+	EXIT_FAILURE = 1 // The definition or the input has errors, or a file can't be written
+	EXIT_USAGE   = 2 // Unknown command, flag or missing arguments
+)
+
+// Streams a command reads from and writes to, so tests can run commands in process.
+type stdio struct {
+	in       io.Reader
+	out, err io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, std stdio) int
+}
+
+var commands = []command{
+	{"generate", "write the lexer of a definition", generateCommand},
+	{"check", "validate a definition and print its diagnostics", checkCommand},
+	{"run", "tokenize a file with a definition", runCommand},
+	{"graph", "draw the DFA or the syntax tree of a definition", graphCommand},
+	{"watch", "tokenize a file again each time it or its definition changes", watchCommand},
+	{"stats", "print the size of the DFA of a definition", statsCommand},
+	{"fmt", "reformat a definition", fmtCommand},
+	{"verify", "check generated lexers are up to date", verifyCommand},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], stdio{in: os.Stdin, out: os.Stdout, err: os.Stderr}))
+}
+
+func run(args []string, std stdio) int {
+	if len(args) == 0 {
+		usage(std.err)
+		return EXIT_USAGE
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(std.out)
+		return EXIT_OK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], std)
+		}
+	}
+	fmt.Fprintf(std.err, "yaalex: unknown command %q\n", args[0])
+	usage(std.err)
+	return EXIT_USAGE
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: yaalex <command> [flags] [arguments]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun \"yaalex <command> -help\" for the flags of a command.")
+}
+
+// Flags of a command.
+type flagSet struct {
+	*flag.FlagSet
+	std stdio
+}
+
+// Returns the flags of a command, which print its usage to stderr on errors.
+func newFlagSet(name, arguments, description string, std stdio) *flagSet {
+	flags := &flagSet{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError), std: std}
+	flags.SetOutput(std.err)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: yaalex %s [flags] %s\n\n%s\n", name, arguments, description)
+		hasFlags := false
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(flags.Output(), "\nflags:")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+// Parses the flags of a command, which may come before or after its arguments, and
+// checks it has between min and max arguments, any number over min if max is -1. If ok is false, the command must
+// exit with code.
+func (flags *flagSet) parse(args []string, min, max int) (arguments []string, code int, ok bool) {
+	// The usage was asked for, so it goes to stdout
+	for _, arg := range args {
+		if arg == "-h" || arg == "-help" || arg == "--help" {
+			flags.SetOutput(flags.std.out)
+			flags.Usage()
+			return nil, EXIT_OK, false
+		}
+	}
+
+	arguments = make([]string, 0)
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, EXIT_USAGE, false
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		arguments = append(arguments, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(arguments) < min || max >= 0 && len(arguments) > max {
+		fmt.Fprintf(flags.Output(), "yaalex %s: expected %s, got %d\n", flags.Name(), argumentCount(min, max), len(arguments))
+		flags.Usage()
+		return nil, EXIT_USAGE, false
+	}
+	return arguments, EXIT_OK, true
+}
+
+func argumentCount(min, max int) string {
+	switch {
+	case max < 0 && min == 1:
+		return "at least 1 argument"
+	case max < 0:
+		return fmt.Sprintf("at least %d arguments", min)
+	case min == max && min == 1:
+		return "1 argument"
+	case min == max:
+		return fmt.Sprintf("%d arguments", min)
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+// Opens the file at path to read, stdin if it is "-".
+func openInput(path string, std stdio) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(std.in), nil
+	}
+	return os.Open(path)
+}
+
+// Writes data to the file at path, stdout if it is "-".
+func writeOutput(path string, data []byte, std stdio) error {
+	if path == "-" {
+		_, err := std.out.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Name of a file in messages.
+func displayName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
+
+// Prints the error of a command and returns its exit code.
+func fail(std stdio, name string, err error) int {
+	message := strings.TrimSpace(err.Error())
+	fmt.Fprintf(std.err, "yaalex %s: %s\n", name, message)
+	return EXIT_FAILURE
+}
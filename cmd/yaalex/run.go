@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+	"github.com/DanielRasho/Lexer/runtime"
+)
+
+func runCommand(args []string, std stdio) int {
+	flags := newFlagSet("run", "<spec.lex> [input]", "Tokenizes the input (stdin by default) with the rules of the definition, without\ngenerating a lexer. Prints a token per line: its offset, its name and its lexeme.\nThe name is what the action returns if it is \"{ return NAME }\", the rule otherwise.", std)
+	constructionFlag := flags.String("construction", string(dfa.DIRECT_CONSTRUCTION), "DFA construction: direct or thompson")
+	invalidUTF8Flag := flags.String("invalid-utf8", string(generator.INVALID_UTF8_ERROR), "How invalid UTF-8 is read: error (INVALID_UTF8 tokens) or replace (read as U+FFFD)")
+	arguments, code, ok := flags.parse(args, 1, 2)
+	if !ok {
+		return code
+	}
+	inputPath := "-"
+	if len(arguments) == 2 {
+		inputPath = arguments[1]
+	}
+	if arguments[0] == "-" && inputPath == "-" {
+		fmt.Fprintln(std.err, "yaalex run: the definition and the input can't both be read from stdin")
+		return EXIT_USAGE
+	}
+
+	invalidUTF8, err := generator.ParseInvalidUTF8Policy(*invalidUTF8Flag)
+	if err != nil {
+		return fail(std, "run", err)
+	}
+	scanner, err := loadScanner(arguments[0], *constructionFlag, std)
+	if err != nil {
+		return fail(std, "run", err)
+	}
+	scanner.ReplaceInvalidUTF8 = invalidUTF8 == generator.INVALID_UTF8_REPLACE
+
+	input, err := openInput(inputPath, std)
+	if err != nil {
+		return fail(std, "run", err)
+	}
+	defer input.Close()
+	scanner.Reset(input)
+
+	output := bufio.NewWriter(std.out)
+	defer output.Flush()
+	for {
+		token, err := scanner.GetNextToken()
+		if err == io.EOF {
+			return EXIT_OK
+		} else if notFound, isNotFound := err.(*runtime.PatternNotFound); isNotFound {
+			output.Flush()
+			fmt.Fprintf(std.err, "%s:%d:%d: no rule recognizes %q\n", displayName(inputPath), notFound.Line, notFound.Column, notFound.Pattern)
+			return EXIT_FAILURE
+		} else if err != nil {
+			output.Flush()
+			return fail(std, "run", err)
+		}
+		fmt.Fprintf(output, "%d\t%s\n", token.Offset, tokenText(token))
+	}
+}
+
+// Builds the lexer of a definition and loads it in a scanner of the runtime.
+func loadScanner(path, construction string, std stdio) (*runtime.Scanner, error) {
+	lexer, err := buildSpec(path, construction, std)
+	if err != nil {
+		return nil, err
+	}
+	automaton, err := dfa.EncodeBinary(lexer.Automata)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.Load(automaton)
+}
+
+// Returns the name and the lexeme of a token, as run prints them after its offset.
+func tokenText(token runtime.Token) string {
+	return fmt.Sprintf("%s\t%q", tokenName(token), token.Bytes())
+}
+
+func tokenName(token runtime.Token) string {
+	switch {
+	case token.Rule == runtime.INVALID_UTF8:
+		return "INVALID_UTF8"
+	case token.Name == "":
+		return "rule " + strconv.Itoa(token.Rule+1)
+	}
+	return token.Name
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+)
+
+func statsCommand(args []string, std stdio) int {
+	flags := newFlagSet("stats", "<spec.lex>", "Prints the size of the DFA of the definition, over runes and over the bytes of UTF-8.", std)
+	constructionFlag := flags.String("construction", string(dfa.DIRECT_CONSTRUCTION), "DFA construction: direct or thompson")
+	arguments, code, ok := flags.parse(args, 1, 1)
+	if !ok {
+		return code
+	}
+
+	lexer, err := buildSpec(arguments[0], *constructionFlag, std)
+	if err != nil {
+		return fail(std, "stats", err)
+	}
+	automata := lexer.Automata
+	byteDFA := dfa.CompileUTF8(automata)
+
+	transitions := 0
+	for _, state := range automata.States {
+		transitions += len(state.Transitions) + len(state.Ranges)
+	}
+	byteTransitions := 0
+	for _, state := range byteDFA.States {
+		byteTransitions += len(state.Transitions)
+	}
+
+	table := tabwriter.NewWriter(std.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "rules\t%d\n", len(automata.Rules))
+	fmt.Fprintf(table, "states\t%d\n", len(automata.States))
+	fmt.Fprintf(table, "transitions\t%d\n", transitions)
+	fmt.Fprintf(table, "alphabet\t%d\tintervals of runes the DFA tells apart\n", alphabetSize(automata))
+	fmt.Fprintf(table, "byte states\t%d\n", len(byteDFA.States))
+	fmt.Fprintf(table, "byte transitions\t%d\n", byteTransitions)
+	fmt.Fprintf(table, "byte classes\t%d\n", byteDFA.NumClasses)
+	if err := table.Flush(); err != nil {
+		return fail(std, "stats", err)
+	}
+	return EXIT_OK
+}
+
+// Returns the number of intervals of runes that some state moves with, where all
+// the runes of each one move every state to the same state.
+func alphabetSize(automata *dfa.DFA) int {
+	// Each interval starts at a rune where a transition starts, or after one where it ends
+	type interval struct{ from, to rune }
+	transitions := make([]interval, 0)
+	for _, state := range automata.States {
+		for symbol := range state.Transitions {
+			r, _ := utf8.DecodeRuneInString(symbol)
+			transitions = append(transitions, interval{r, r})
+		}
+		for _, t := range state.Ranges {
+			transitions = append(transitions, interval{t.From, t.To})
+		}
+	}
+	bounds := make(map[rune]bool)
+	for _, t := range transitions {
+		bounds[t.from] = true
+		bounds[t.to+1] = true
+	}
+	starts := make([]rune, 0, len(bounds))
+	for r := range bounds {
+		starts = append(starts, r)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	// Only the intervals inside some transition are part of the alphabet
+	size := 0
+	for i := 0; i+1 < len(starts); i++ {
+		for _, t := range transitions {
+			if t.from <= starts[i] && starts[i] <= t.to {
+				size++
+				break
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+)
+
+func verifyCommand(args []string, std stdio) int {
+	flags := newFlagSet("verify", "<lexer>...", "Checks the lexers are the ones yaalex generates now from their definitions, with the\noptions in their stamp. Fails if one is stale: its definition changed, it was\ngenerated by another version or it was edited.", std)
+	specFlag := flags.String("spec", "", "Definition of the lexer, the one in its stamp by default. Only for one lexer")
+	arguments, code, ok := flags.parse(args, 1, -1)
+	if !ok {
+		return code
+	}
+	if *specFlag != "" && len(arguments) > 1 {
+		fmt.Fprintln(std.err, "yaalex verify: -spec can only be used with one lexer")
+		return EXIT_USAGE
+	}
+
+	code = EXIT_OK
+	for _, lexer := range arguments {
+		var stale *generator.StaleError
+		if err := generator.Verify(lexer, *specFlag); errors.As(err, &stale) {
+			fmt.Fprintf(std.err, "%s, run yaalex generate or go generate\n", err)
+			code = EXIT_FAILURE
+		} else if errors.Is(err, generator.ErrNoSpec) {
+			fmt.Fprintf(std.err, "yaalex verify: %s, give it with -spec\n", err)
+			code = EXIT_FAILURE
+		} else if err != nil {
+			code = fail(std, "verify", err)
+		} else {
+			fmt.Fprintf(std.out, "%s is up to date\n", lexer)
+		}
+	}
+	return code
+}
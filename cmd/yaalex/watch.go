@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+	"github.com/DanielRasho/Lexer/runtime"
+)
+
+func watchCommand(args []string, std stdio) int {
+	flags := newFlagSet("watch", "<spec.lex>", "Tokenizes the input each time it or the definition changes, and prints the tokens\nthat changed since the last run and the diagnostics of the definition. Stops with Ctrl-C.", std)
+	inputFlag := flags.String("input", "", "File to tokenize")
+	intervalFlag := flags.Duration("interval", 300*time.Millisecond, "How often the files are checked for changes")
+	constructionFlag := flags.String("construction", string(dfa.DIRECT_CONSTRUCTION), "DFA construction: direct or thompson")
+	invalidUTF8Flag := flags.String("invalid-utf8", string(generator.INVALID_UTF8_ERROR), "How invalid UTF-8 is read: error (INVALID_UTF8 tokens) or replace (read as U+FFFD)")
+	arguments, code, ok := flags.parse(args, 1, 1)
+	if !ok {
+		return code
+	}
+	if *inputFlag == "" || arguments[0] == "-" || *inputFlag == "-" {
+		fmt.Fprintln(std.err, "yaalex watch: the definition and -input must be files")
+		return EXIT_USAGE
+	}
+
+	construction, err := dfa.ParseConstruction(*constructionFlag)
+	if err != nil {
+		return fail(std, "watch", err)
+	}
+	invalidUTF8, err := generator.ParseInvalidUTF8Policy(*invalidUTF8Flag)
+	if err != nil {
+		return fail(std, "watch", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	w := &watcher{spec: arguments[0], input: *inputFlag, construction: construction, invalidUTF8: invalidUTF8, output: std.out}
+	ticker := time.NewTicker(*intervalFlag)
+	defer ticker.Stop()
+	for {
+		w.poll()
+		select {
+		case <-ctx.Done():
+			return EXIT_OK
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tokenizes the input with the definition again when one of them changes.
+type watcher struct {
+	spec, input  string
+	construction dfa.Construction
+	invalidUTF8  generator.InvalidUTF8Policy
+	output       io.Writer
+
+	specData, inputData []byte           // Contents of the files in the last run
+	scanner             *runtime.Scanner // Built from specData, nil if it has errors
+	tokens              []watchedToken   // Tokens of the last run
+	runs                int
+	readErr             string // Error reading the files in the last poll, printed once
+}
+
+// A token as run prints it. Tokens are compared by their text, since editing the input
+// moves the offset of all the tokens after the edit.
+type watchedToken struct {
+	offset int
+	text   string
+}
+
+// Reads the files, and runs if they changed since the last run. Returns if it ran.
+func (w *watcher) poll() bool {
+	// Files being written may be missing for a moment, the next poll reads them again
+	specData, err := os.ReadFile(w.spec)
+	var inputData []byte
+	if err == nil {
+		inputData, err = os.ReadFile(w.input)
+	}
+	if err != nil {
+		if err.Error() != w.readErr {
+			fmt.Fprintf(w.output, "error: %s\n", err)
+			w.readErr = err.Error()
+		}
+		return false
+	}
+	w.readErr = ""
+
+	specChanged := w.runs == 0 || !bytes.Equal(specData, w.specData)
+	inputChanged := !bytes.Equal(inputData, w.inputData)
+	if !specChanged && !inputChanged {
+		return false
+	}
+	w.runs++
+	fmt.Fprintf(w.output, "== run %d at %s\n", w.runs, time.Now().Format(time.TimeOnly))
+
+	if specChanged {
+		w.specData = specData
+		w.scanner = nil
+		lexer, err := generator.Build(bytes.NewReader(specData), w.spec, generator.Options{Construction: w.construction})
+		if err != nil {
+			fmt.Fprintf(w.output, "%s: error: %s\n", w.spec, err)
+			return true
+		}
+		for _, warning := range diagnostics(lexer) {
+			fmt.Fprintf(w.output, "%s: warning: %s\n", w.spec, warning)
+		}
+		automaton, err := dfa.EncodeBinary(lexer.Automata)
+		if err == nil {
+			w.scanner, err = runtime.Load(automaton)
+		}
+		if err != nil {
+			fmt.Fprintf(w.output, "%s: error: %s\n", w.spec, err)
+			return true
+		}
+		w.scanner.ReplaceInvalidUTF8 = w.invalidUTF8 == generator.INVALID_UTF8_REPLACE
+	}
+	w.inputData = inputData
+	if w.scanner == nil {
+		return true // The definition has errors, which were already printed
+	}
+
+	tokens := make([]watchedToken, 0, len(w.tokens))
+	w.scanner.Reset(bytes.NewReader(inputData))
+	for {
+		token, err := w.scanner.GetNextToken()
+		if err == io.EOF {
+			break
+		} else if notFound, isNotFound := err.(*runtime.PatternNotFound); isNotFound {
+			fmt.Fprintf(w.output, "%s:%d:%d: no rule recognizes %q\n", w.input, notFound.Line, notFound.Column, notFound.Pattern)
+			break
+		} else if err != nil {
+			fmt.Fprintf(w.output, "%s: error: %s\n", w.input, err)
+			break
+		}
+		tokens = append(tokens, watchedToken{offset: token.Offset, text: tokenText(token)})
+	}
+
+	removed, added := 0, 0
+	for _, edit := range diffTokens(w.tokens, tokens) {
+		if edit.added {
+			added++
+			fmt.Fprintf(w.output, "+ %d\t%s\n", edit.token.offset, edit.token.text)
+		} else {
+			removed++
+			fmt.Fprintf(w.output, "- %d\t%s\n", edit.token.offset, edit.token.text)
+		}
+	}
+	fmt.Fprintf(w.output, "%d tokens, %d added, %d removed\n", len(tokens), added, removed)
+	w.tokens = tokens
+	return true
+}
+
+// A token removed from the last run, or added in the new one.
+type tokenEdit struct {
+	token watchedToken
+	added bool
+}
+
+// Largest product of the number of tokens of two runs diffed token by token, after
+// removing the tokens they start and end with. Larger ones are printed as all removed
+// and then all added.
+const MAX_DIFF_CELLS = 4_000_000
+
+// Returns the tokens to remove from before and add to it to get after, in the order of the input.
+func diffTokens(before, after []watchedToken) []tokenEdit {
+	// Edits are usually in one place, so most tokens are the same at the start and the end
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix].text == after[prefix].text {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix].text == after[len(after)-1-suffix].text {
+		suffix++
+	}
+	before, after = before[prefix:len(before)-suffix], after[prefix:len(after)-suffix]
+
+	edits := make([]tokenEdit, 0)
+	if len(before)*len(after) > MAX_DIFF_CELLS {
+		for _, token := range before {
+			edits = append(edits, tokenEdit{token: token})
+		}
+		for _, token := range after {
+			edits = append(edits, tokenEdit{token: token, added: true})
+		}
+		return edits
+	}
+
+	// common[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i].text == after[j].text {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i].text == after[j].text:
+			i++
+			j++
+		case i < len(before) && (j == len(after) || common[i+1][j] >= common[i][j+1]):
+			edits = append(edits, tokenEdit{token: before[i]})
+			i++
+		default:
+			edits = append(edits, tokenEdit{token: after[j], added: true})
+			j++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSpec = `%{
+const (
+	ID = iota
+	NUM
+	IF
+)
+%}
+{
+}
+%%
+[a-z]+  { return ID }
+[0-9]+  { return NUM }
+"if"  { return IF }
+" "  {}
+%%
+`
+
+// Runs yaalex with stdin as input, returning its exit code, stdout and stderr.
+func runYaalex(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, stdio{in: strings.NewReader(stdin), out: &stdout, err: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.lex")
+	if err := os.WriteFile(spec, []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args   []string
+		stdin  string
+		code   int
+		stdout string // Expected to be in stdout
+		stderr string // Expected to be in stderr
+	}{
+		{args: []string{}, code: EXIT_USAGE, stderr: "usage: yaalex"},
+		{args: []string{"compile"}, code: EXIT_USAGE, stderr: `unknown command "compile"`},
+		{args: []string{"--help"}, code: EXIT_OK, stdout: "commands:"},
+		{args: []string{"run", "--help"}, code: EXIT_OK, stdout: "usage: yaalex run"},
+		{args: []string{"stats", "-unknown", spec}, code: EXIT_USAGE, stderr: "flag provided but not defined"},
+		{args: []string{"check"}, code: EXIT_USAGE, stderr: "expected 1 argument"},
+		{args: []string{"generate", "-q", "-"}, stdin: testSpec, code: EXIT_OK, stdout: "package main"},
+		{args: []string{"generate", "-q", "-target", "c", spec}, code: EXIT_OK, stdout: "int yylex(void)"},
+		{args: []string{"generate", "-v", spec}, code: EXIT_OK, stderr: "phase=parse"},
+		{args: []string{"generate", "-q", filepath.Join(dir, "missing.lex")}, code: EXIT_FAILURE, stderr: "missing.lex"},
+		{args: []string{"generate", "-q", "-"}, stdin: "%%\n[a-z  {}\n%%\n", code: EXIT_FAILURE, stderr: "yaalex generate: "},
+		{args: []string{"check", spec}, code: EXIT_OK, stderr: `rule 3 "if" is never recognized`},
+		{args: []string{"check", "-strict", spec}, code: EXIT_FAILURE},
+		{args: []string{"check", "-"}, stdin: "%%\n[a-z  {}\n%%\n", code: EXIT_FAILURE, stderr: "<stdin>: error:"},
+		{args: []string{"run", spec}, stdin: "ab 12 if", code: EXIT_OK, stdout: "0\tID\t\"ab\"\n3\tNUM\t\"12\"\n6\tID\t\"if\"\n"},
+		{args: []string{"run", spec, "-"}, stdin: "ab ?", code: EXIT_FAILURE, stderr: `<stdin>:1:4: no rule recognizes "?"`},
+		{args: []string{"run", "-"}, code: EXIT_USAGE},
+		{args: []string{"graph", "-format", "mermaid", spec}, code: EXIT_OK, stdout: "flowchart LR"},
+		{args: []string{"graph", "-ast", spec, "-o", filepath.Join(dir, "tree.svg")}, code: EXIT_OK},
+		{args: []string{"graph", "-format", "png", spec}, code: EXIT_FAILURE, stderr: "-o path ending in .png"},
+		{args: []string{"stats", spec}, code: EXIT_OK, stdout: "states"},
+		{args: []string{"generate", "-q", "-o", filepath.Join(dir, "lexer.go"), spec}, code: EXIT_OK},
+		{args: []string{"verify", filepath.Join(dir, "lexer.go")}, code: EXIT_OK, stdout: "is up to date"},
+		{args: []string{"verify", "-spec", filepath.Join(dir, "tree.svg"), filepath.Join(dir, "lexer.go")}, code: EXIT_FAILURE, stderr: "is stale"},
+		{args: []string{"verify"}, code: EXIT_USAGE, stderr: "expected at least 1 argument"},
+		{args: []string{"generate", "-q", "-o", filepath.Join(dir, "stdin.go"), "-"}, stdin: testSpec, code: EXIT_OK},
+		{args: []string{"verify", filepath.Join(dir, "stdin.go")}, code: EXIT_FAILURE, stderr: "give it with -spec"},
+		{args: []string{"verify", "-spec", spec, filepath.Join(dir, "stdin.go")}, code: EXIT_OK, stdout: "is up to date"},
+		{args: []string{"fmt", "-l", spec}, code: EXIT_OK, stdout: spec},
+		{args: []string{"fmt", "-target", "c", "-"}, stdin: "%%\n[0-9]+  {return 1;}\n%%\n", code: EXIT_OK, stdout: "[0-9]+  {return 1;}\n"},
+		{args: []string{"fmt", "-"}, stdin: "%%\n[a-z]+  { return ID }\n\" \"  {}  // Spaces\n%%\n", code: EXIT_OK,
+			stdout: "%%\n[a-z]+  { return ID }\n\" \"     {}             // Spaces\n%%\n"},
+	}
+
+	for _, c := range cases {
+		code, stdout, stderr := runYaalex(c.stdin, c.args...)
+		if code != c.code || !strings.Contains(stdout, c.stdout) || !strings.Contains(stderr, c.stderr) {
+			t.Errorf("yaalex %s: expected exit %d with %q in stdout and %q in stderr\ngot %d\nstdout: %.300s\nstderr: %.300s",
+				strings.Join(c.args, " "), c.code, c.stdout, c.stderr, code, stdout, stderr)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tree.svg")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWatcherPrintsTheTokensThatChanged(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.lex")
+	input := filepath.Join(dir, "input.txt")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(spec, testSpec)
+	write(input, "ab 12 cd")
+
+	var output bytes.Buffer
+	w := &watcher{spec: spec, input: input, construction: "direct", invalidUTF8: "error", output: &output}
+	steps := []struct {
+		change   func()
+		ran      bool
+		expected []string // Lines of the output, without the header of the run
+	}{
+		{func() {}, true, []string{"+ 0\tID\t\"ab\"", "+ 3\tNUM\t\"12\"", "+ 6\tID\t\"cd\"", "3 tokens, 3 added, 0 removed"}},
+		{func() {}, false, nil},
+		{func() { write(input, "ab 7 12 cd") }, true, []string{"+ 3\tNUM\t\"7\"", "4 tokens, 1 added, 0 removed"}},
+		{func() { write(spec, strings.Replace(testSpec, "[0-9]+  { return NUM }", "[0-9]  { return NUM }", 1)) }, true,
+			[]string{`rule 3 "if" is never recognized`, "- 5\tNUM\t\"12\"", "+ 5\tNUM\t\"1\"", "+ 6\tNUM\t\"2\"", "5 tokens, 2 added, 1 removed"}},
+		{func() { write(spec, "%%\n[a-z  {}\n%%\n") }, true, []string{"spec.lex: error:"}},
+		{func() { write(spec, testSpec); write(input, "ab ?") }, true, []string{`rule 3 "if" is never recognized`, "input.txt:1:4: no rule recognizes \"?\""}},
+		{func() { os.Remove(input) }, false, []string{"error: open"}},
+	}
+
+	for i, step := range steps {
+		step.change()
+		output.Reset()
+		if ran := w.poll(); ran != step.ran {
+			t.Fatalf("step %d: expected ran %v, got %v", i, step.ran, ran)
+		}
+		for _, line := range step.expected {
+			if !strings.Contains(output.String(), line) {
+				t.Errorf("step %d: expected %q in the output\n%s", i, line, output.String())
+			}
+		}
+	}
+}
@@ -1,5 +1,3 @@
 module github.com/DanielRasho/Lexer
 
 go 1.23.6
-
-require github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3 // indirect
@@ -0,0 +1,166 @@
+package postfix
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// This file contains the predefined classes of characters (POSIX and Unicode)
+// and the operations over sets of runes used to build classes.
+
+// POSIX classes allowed within a class. Ex: [[:alpha:]_]
+// They are Unicode aware, so [:alpha:] also matches "ñ" or "λ".
+var POSIX_CLASSES = map[string][]*unicode.RangeTable{
+	"alpha":  {unicode.L},
+	"digit":  {unicode.Nd},
+	"alnum":  {unicode.L, unicode.Nd},
+	"upper":  {unicode.Lu},
+	"lower":  {unicode.Ll},
+	"space":  {unicode.White_Space},
+	"blank":  {unicode.Zs, {R16: []unicode.Range16{{Lo: '\t', Hi: '\t', Stride: 1}}}},
+	"punct":  {unicode.P},
+	"cntrl":  {unicode.Cc},
+	"print":  {unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs},
+	"graph":  {unicode.L, unicode.M, unicode.N, unicode.P, unicode.S},
+	"xdigit": {{R16: []unicode.Range16{{Lo: '0', Hi: '9', Stride: 1}, {Lo: 'A', Hi: 'F', Stride: 1}, {Lo: 'a', Hi: 'f', Stride: 1}}}},
+}
+
+// Shorthand classes, used as "\d", "\w", "\s". Its uppercase version is the negation. Ex: "\D"
+var SHORTHAND_CLASSES = map[string][]*unicode.RangeTable{
+	"d": {unicode.Nd},
+	"w": {unicode.L, unicode.Nd, unicode.Pc},
+	"s": {unicode.White_Space},
+}
+
+// Returns the set of runes for an Unicode category, script or property. Ex: "L", "Greek", "White_Space"
+func unicodeClassRanges(name string) ([]RuneRange, error) {
+	if name == "Any" {
+		return negateRanges(nil), nil
+	}
+	if table, exist := unicode.Categories[name]; exist {
+		return rangesFromTables(table), nil
+	}
+	if table, exist := unicode.Scripts[name]; exist {
+		return rangesFromTables(table), nil
+	}
+	if table, exist := unicode.Properties[name]; exist {
+		return rangesFromTables(table), nil
+	}
+	return nil, fmt.Errorf("unknown unicode class %q", name)
+}
+
+// Returns the set of runes for a POSIX class name. Ex: "alpha"
+func posixClassRanges(name string) ([]RuneRange, error) {
+	tables, exist := POSIX_CLASSES[name]
+	if !exist {
+		return nil, fmt.Errorf("unknown POSIX class [:%s:]", name)
+	}
+	return rangesFromTables(tables...), nil
+}
+
+// Converts unicode range tables to a sorted list of ranges without overlapping.
+func rangesFromTables(tables ...*unicode.RangeTable) []RuneRange {
+	ranges := make([]RuneRange, 0)
+	for _, table := range tables {
+		for _, r := range table.R16 {
+			ranges = appendStrideRange(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		for _, r := range table.R32 {
+			ranges = appendStrideRange(ranges, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+	}
+	return normalizeRanges(ranges)
+}
+
+// Range tables can skip runes (Stride > 1), in that case each rune is its own range.
+func appendStrideRange(ranges []RuneRange, lo, hi, stride rune) []RuneRange {
+	if stride == 1 {
+		return append(ranges, RuneRange{From: lo, To: hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		ranges = append(ranges, RuneRange{From: r, To: r})
+	}
+	return ranges
+}
+
+// Sorts the ranges and merges the ones that overlap or are next to each other.
+// Ex: [a-c, b-f, g-h, x-x] => [a-h, x-x]
+func normalizeRanges(ranges []RuneRange) []RuneRange {
+	sorted := make([]RuneRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].From < sorted[j].From })
+
+	result := make([]RuneRange, 0, len(sorted))
+	for _, r := range sorted {
+		if last := len(result) - 1; last >= 0 && r.From <= result[last].To+1 {
+			result[last].To = max(result[last].To, r.To)
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// Returns all runes NOT contained in ranges. Surrogates are never included
+// since they are not valid runes by themselves.
+func negateRanges(ranges []RuneRange) []RuneRange {
+	result := make([]RuneRange, 0, len(ranges)+1)
+	next := rune(0)
+	excluded := append([]RuneRange{{From: 0xD800, To: 0xDFFF}}, ranges...)
+	for _, r := range normalizeRanges(excluded) {
+		if r.From > next {
+			result = append(result, RuneRange{From: next, To: r.From - 1})
+		}
+		next = r.To + 1
+	}
+	if next <= unicode.MaxRune {
+		result = append(result, RuneRange{From: next, To: unicode.MaxRune})
+	}
+	return result
+}
+
+// Creates a symbol that matches any rune within ranges.
+func newClassSymbol(value string, ranges []RuneRange) Symbol {
+	return Symbol{
+		Value:      value,
+		Precedence: 60,
+		IsOperator: false,
+		Action:     Action{Priority: -1},
+		Ranges:     normalizeRanges(ranges),
+	}
+}
+
+// Adds to ranges all the other cases of its runes, as defined by unicode.SimpleFold.
+// Ex: [a-c, 1] => [A-C, a-c, 1]
+func foldRanges(ranges []RuneRange) []RuneRange {
+	result := make([]RuneRange, len(ranges))
+	copy(result, ranges)
+
+	// Only runes within unicode.CaseRanges have other cases.
+	for _, r := range ranges {
+		for _, caseRange := range unicode.CaseRanges {
+			from := max(r.From, rune(caseRange.Lo))
+			to := min(r.To, rune(caseRange.Hi))
+			for c := from; c <= to; c++ {
+				for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+					result = append(result, RuneRange{From: f, To: f})
+				}
+			}
+		}
+	}
+	return normalizeRanges(result)
+}
+
+// Expands a character to all its cases. Ex: "s" => [sſS]
+// Characters without other cases remain the same.
+func foldLiteral(r rune) Regex {
+	cases := string(r)
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		cases += string(f)
+	}
+	if cases == string(r) {
+		return Literal{Value: r}
+	}
+	return Class{Value: "[" + cases + "]", Ranges: foldRanges([]RuneRange{{From: r, To: r}})}
+}
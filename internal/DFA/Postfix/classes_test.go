@@ -0,0 +1,76 @@
+package postfix
+
+import (
+	"testing"
+)
+
+func classContains(class Class, r rune) bool {
+	for _, rr := range class.Ranges {
+		if rr.From <= r && r <= rr.To {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPredefinedClasses(t *testing.T) {
+	cases := []struct {
+		expresion string
+		matches   string
+		rejects   string
+	}{
+		{`[[:alpha:]]`, "aZñλж", "1_ -"},
+		{`[[:digit:]_]`, "09٣_", "a-"},
+		{`[[:space:]]`, " \t\n ", "a_"},
+		{`[[:xdigit:]]`, "09afAF", "gG"},
+		{`\d`, "0٣", "a"},
+		{`\w`, "aλ_9", " -"},
+		{`\s`, " \n", "a"},
+		{`\D`, "a ", "0٣"},
+		{`\p{L}`, "aλ", "1 "},
+		{`\p{Greek}`, "λΩ", "a"},
+		{`\P{Greek}`, "a", "λ"},
+		{`[^a-c]`, "dA^", "abc"},
+		{`[\^a]`, "^a", "b"},
+		{`[\d_]`, "1_", "a"},
+	}
+
+	for _, c := range cases {
+		regex, err := Parse(c.expresion, ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		class, isClass := regex.(Class)
+		if !isClass {
+			t.Errorf("%s: expected a single class, got %v", c.expresion, regex)
+			continue
+		}
+		for _, r := range c.matches {
+			if !classContains(class, r) {
+				t.Errorf("%s: expected to match %q", c.expresion, r)
+			}
+		}
+		for _, r := range c.rejects {
+			if classContains(class, r) {
+				t.Errorf("%s: expected not to match %q", c.expresion, r)
+			}
+		}
+	}
+}
+
+func TestUnknownClasses(t *testing.T) {
+	for _, expresion := range []string{`[[:foo:]]`, `[[:alpha]`, `\p{Klingon}`, `\p{L`, `\pL`} {
+		if _, err := Parse(expresion, ParseOptions{}); err == nil {
+			t.Errorf("%s: expected an error", expresion)
+		}
+	}
+}
+
+func TestNormalizeRanges(t *testing.T) {
+	result := normalizeRanges([]RuneRange{{'x', 'x'}, {'b', 'f'}, {'a', 'c'}, {'g', 'h'}})
+	expected := []RuneRange{{'a', 'h'}, {'x', 'x'}}
+	if len(result) != len(expected) || result[0] != expected[0] || result[1] != expected[1] {
+		t.Errorf("expected %v got %v", expected, result)
+	}
+}
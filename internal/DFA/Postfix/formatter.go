@@ -1,119 +1,133 @@
 package postfix
 
-// This file contains logic specifically to manipulating a list of symbols
-// or strings
-
-// Convert a sequence of raw symbols to a list of symbols, supports escaped characters.
-func convertToSymbols(expresion []RawSymbol) ([]Symbol, error) {
-	finalSymbols := make([]Symbol, 0, len(expresion))
-
-	for i := 0; i < len(expresion); {
-		t1, _ := getRawSymbolInfo(expresion, i)
-		t2, t2Exist := getRawSymbolInfo(expresion, i+1)
-
-		if t1.Value == ESCAPE_SYMBOL {
-			if t2Exist {
-				finalSymbols = append(finalSymbols, Symbol{
-					Value:      t2.Value,
-					Precedence: 60,
-					IsOperator: false})
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file contains the readers of escape sequences, POSIX classes and other
+// multi-character constructs used by the parser.
+
+// Reads the escape sequence starting at index (which must point to an ESCAPE_SYMBOL).
+// Returns the symbol it represents and how many raw symbols were consumed.
+func readEscapeSequence(expresion []RawSymbol, index int) (Symbol, int, error) {
+	t2, t2Exist := getRawSymbolInfo(expresion, index+1)
+	if !t2Exist {
+		return Symbol{}, 0, fmt.Errorf("trailing escape symbol %q", ESCAPE_SYMBOL)
+	}
 
-				i += 2
-				continue
-			}
+	switch t2.Value {
+	case "t":
+		return newLiteralSymbol("\t"), 2, nil
+	case "n":
+		return newLiteralSymbol("\n"), 2, nil
+	case "r":
+		return newLiteralSymbol("\r"), 2, nil
+	case "f":
+		return newLiteralSymbol("\f"), 2, nil
+	case "v":
+		return newLiteralSymbol("\v"), 2, nil
+	case "d", "w", "s":
+		return newClassSymbol(ESCAPE_SYMBOL+t2.Value, rangesFromTables(SHORTHAND_CLASSES[t2.Value]...)), 2, nil
+	case "D", "W", "S":
+		lower := strings.ToLower(t2.Value)
+		return newClassSymbol(ESCAPE_SYMBOL+t2.Value, negateRanges(rangesFromTables(SHORTHAND_CLASSES[lower]...))), 2, nil
+	case "p", "P":
+		// \p{Name}, Name being an unicode category, script or property
+		name, size, err := readBracedSequence(expresion, index+2)
+		if err != nil {
+			return Symbol{}, 0, fmt.Errorf("invalid escape sequence \\%s: %w", t2.Value, err)
 		}
-		if operator, isOperator := OPERATORS[t1.Value]; isOperator {
-			finalSymbols = append(finalSymbols, operator)
-		} else {
-			finalSymbols = append(finalSymbols, Symbol{
-				Value:      t1.Value,
-				Precedence: 60,
-				IsOperator: false,
-				Action:     t1.Action,
-			})
+		ranges, err := unicodeClassRanges(name)
+		if err != nil {
+			return Symbol{}, 0, err
 		}
-		i++
-	}
-
-	return finalSymbols, nil
-}
-
-// Add concatenation symbol to an expresion.
-func addConcatenationSymbols(expresion []Symbol) ([]Symbol, error) {
-
-	formattedTokens := make([]Symbol, 0, len(expresion))
-
-	for i := 0; i < len(expresion); {
-		s1, _ := getSymbolInfo(expresion, i)
-		s2, s2Exist := getSymbolInfo(expresion, i+1)
-
-		// SPECIAL CASE, if Class sctructure encontared skip([abc])
-		if s1.Value == "[" && s1.IsOperator {
-			newIndex := i
-			// Search for the closing class bracket "]"
-			for ; newIndex < len(expresion); newIndex++ {
-				step, _ := getSymbolInfo(expresion, newIndex)
-
-				if step.Value == "]" && step.IsOperator {
-					break
-				}
-
-				formattedTokens = append(formattedTokens, step)
+		if t2.Value == "P" {
+			ranges = negateRanges(ranges)
+		}
+		return newClassSymbol(ESCAPE_SYMBOL+t2.Value+"{"+name+"}", ranges), size + 2, nil
+	case "x":
+		// \xHH, exactly 2 hex digits
+		digits := ""
+		for j := index + 2; j < index+4; j++ {
+			s, exist := getRawSymbolInfo(expresion, j)
+			if !exist || !isHexDigit(s.Value) {
+				return Symbol{}, 0, fmt.Errorf("invalid escape sequence \\x%s, expected 2 hex digits", digits)
 			}
-
-			i = newIndex // To start with the next symbol after the class
-			continue
+			digits += s.Value
 		}
-
-		formattedTokens = append(formattedTokens, s1)
-
-		if s2Exist && shouldAddConcatenationSymbol(s1, s2) {
-			formattedTokens = append(formattedTokens, OPERATORS[CONCAT_SYMBOL])
+		code, _ := strconv.ParseUint(digits, 16, 8)
+		return newLiteralSymbol(string(rune(code))), 4, nil
+	case "u":
+		// \u{H...}, from 1 to 6 hex digits
+		digits, size, err := readBracedSequence(expresion, index+2)
+		if err != nil {
+			return Symbol{}, 0, fmt.Errorf("invalid escape sequence \\u: %w", err)
 		}
-
-		i++
+		code, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(code)) {
+			return Symbol{}, 0, fmt.Errorf("invalid escape sequence \\u{%s}, not a valid code point", digits)
+		}
+		return newLiteralSymbol(string(rune(code))), size + 2, nil
 	}
 
-	return formattedTokens, nil
+	// Any other escaped character stands for itself. Ex: \\, \", \+
+	return newLiteralSymbol(t2.Value), 2, nil
 }
 
-// Helper function to check that if given to symbols, a concatenation symbol
-// should be added in between.
-func shouldAddConcatenationSymbol(s1, s2 Symbol) bool {
-
-	if s2.Value == "" {
-		return false
+// Reads a sequence like "{abc}" starting at index. Returns its content ("abc")
+// and how many raw symbols were consumed (including braces).
+func readBracedSequence(expresion []RawSymbol, index int) (string, int, error) {
+	if s, _ := getRawSymbolInfo(expresion, index); s.Value != "{" {
+		return "", 0, fmt.Errorf("expected \"{\"")
 	}
-
-	// If both are open or close parenthesis, false
-	if (s1.IsOperator && s2.IsOperator) &&
-		((s1.Value == "(" && s2.Value == "(") ||
-			(s1.Value == ")" && s2.Value == ")")) {
-		return false
+	content := ""
+	for j := index + 1; ; j++ {
+		s, exist := getRawSymbolInfo(expresion, j)
+		if !exist {
+			return "", 0, fmt.Errorf("missing \"}\" after \"{%s\"", content)
+		}
+		if s.Value == "}" {
+			if content == "" {
+				return "", 0, fmt.Errorf("empty \"{}\"")
+			}
+			return content, j - index + 1, nil
+		}
+		content += s.Value
 	}
+}
 
-	// If the S1 is Operator :
-	// 	need more than 1 operands, or
-	// 	is an open parenthesis, or
-	// 	need less than one operand and the next character is an operator
-	if s1.IsOperator {
-		if s1.Operands > 1 ||
-			(s1.Value == "(" && !s2.IsOperator) ||
-			(s1.Operands < 1 && s2.IsOperator) {
-			return false
+// Reads a POSIX class like "[:alpha:]" starting at index.
+// Returns its symbol and how many raw symbols were consumed.
+func readPosixClass(expresion []RawSymbol, index int) (Symbol, int, error) {
+	name := ""
+	for j := index + 2; ; j++ {
+		s, exist := getRawSymbolInfo(expresion, j)
+		next, _ := getRawSymbolInfo(expresion, j+1)
+		if !exist {
+			return Symbol{}, 0, fmt.Errorf("unterminated POSIX class [:%s", name)
 		}
+		if s.Value == ":" && next.Value == "]" {
+			ranges, err := posixClassRanges(name)
+			if err != nil {
+				return Symbol{}, 0, err
+			}
+			return newClassSymbol("[:"+name+":]", ranges), j - index + 2, nil
+		}
+		name += s.Value
 	}
-	// 	If S2 is an "(" or "[" operator
-	if s2.IsOperator &&
-		((s2.Value == "(") ||
-			(s2.Value == "[")) {
-		return true
-	}
-	if s2.IsOperator { // If s2 is not operand then
-		return false
-	}
+}
 
-	return true
+// Creates a symbol that represents a plain character.
+func newLiteralSymbol(value string) Symbol {
+	return Symbol{Value: value, Precedence: 60, IsOperator: false, Action: Action{Priority: -1}}
+}
+
+// Returns true if s is a single hexadecimal digit.
+func isHexDigit(s string) bool {
+	return len(s) == 1 && strings.ContainsAny(s, "0123456789abcdefABCDEF")
 }
 
 // Returns a token (string) from a given index. For invalid index return empty string and false.
@@ -127,15 +141,3 @@ func getRawSymbolInfo(symbols []RawSymbol, index int) (s RawSymbol, exist bool)
 	exist = true
 	return
 }
-
-// Returns a Symbol from a given index. For invalid index return empty Symbol and false.
-func getSymbolInfo(symbols []Symbol, index int) (s Symbol, exist bool) {
-	if index >= len(symbols) || index < 0 {
-		s = Symbol{}
-		exist = false
-		return
-	}
-	s = symbols[index]
-	exist = true
-	return
-}
@@ -0,0 +1,548 @@
+package postfix
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file contains a recursive descent parser that builds the syntax tree of a regex.
+//
+//	alt    = concat {"|" concat}
+//	concat = repeat {repeat}
+//	repeat = atom {"*" | "+" | "?" | "{" n ["," [m]] "}"}
+//	atom   = char | escape | quoted | class | group | "{" NAME "}"
+//
+// Rules can also use anchors and trailing context (rule.go).
+
+// An error in the syntax of a pattern. Ex: unexpected ")" at column 7 of rule 3
+type SyntaxError struct {
+	Message      string
+	Column       int    // Position within the pattern, starting at 1
+	Rule         int    // Number of the rule the pattern belongs to, starting at 1. 0 if unknown
+	NamedPattern string // Name of the named pattern the error is in, empty if it is in the rule itself
+}
+
+func (e *SyntaxError) Error() string {
+	message := fmt.Sprintf("%s at column %d", e.Message, e.Column)
+	if e.NamedPattern != "" {
+		message += fmt.Sprintf(" of named pattern {%s}", e.NamedPattern)
+	}
+	switch {
+	case e.Rule > 0 && e.NamedPattern != "":
+		message += fmt.Sprintf(" used by rule %d", e.Rule)
+	case e.Rule > 0:
+		message += fmt.Sprintf(" of rule %d", e.Rule)
+	}
+	return message
+}
+
+// Options to parse a pattern.
+type ParseOptions struct {
+	// Letters match any of its cases, as if the pattern was within "(?i:...)".
+	CaseInsensitive bool
+
+	// Returns the expression of a named pattern referenced as "{NAME}",
+	// parsed case-insensitive if the reference is. Nil if there are no named patterns.
+	Resolve func(name string, caseInsensitive bool) (Regex, error)
+}
+
+// Upper limit of repetitions like "a{2,5}", since each one is a copy of its expression.
+const MAX_REPETITIONS = 1000
+
+type parser struct {
+	expresion []RawSymbol
+	pos       int
+	depth     int  // Number of groups open at pos
+	foldCase  bool // If letters must match any of its cases
+	isRule    bool // If "/" and "$" are operators at the top level
+	options   ParseOptions
+}
+
+func newParser(expresion []RawSymbol, options ParseOptions) *parser {
+	return &parser{expresion: expresion, foldCase: options.CaseInsensitive, options: options}
+}
+
+// Parses a regex pattern. Ex: "(a|b)*c"
+func Parse(pattern string, options ParseOptions) (Regex, error) {
+	return ParseRawSymbols(toRawSymbols(pattern), options)
+}
+
+// Parses a sequence of raw symbols. Besides plain characters it can contain
+// the action symbols and anchors the generator joins rules with.
+func ParseRawSymbols(expresion []RawSymbol, options ParseOptions) (Regex, error) {
+	p := newParser(expresion, options)
+	regex, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, p.unexpected()
+	}
+	if regex == nil {
+		return nil, p.errorAt(p.pos, "empty pattern")
+	}
+	return regex, nil
+}
+
+// Converts a pattern to raw symbols, one for each character.
+func toRawSymbols(pattern string) []RawSymbol {
+	raw := make([]RawSymbol, 0, len(pattern))
+	for _, r := range pattern {
+		raw = append(raw, RawSymbol{Value: string(r), Action: Action{Priority: -1}})
+	}
+	return raw
+}
+
+// ==================
+// GRAMMAR
+// ==================
+
+// alt = concat {"|" concat}
+// Returns nil for an empty expression.
+func (p *parser) parseAlt() (Regex, error) {
+	first, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []Regex{first}
+	for p.is("|") {
+		bar := p.pos
+		if options[len(options)-1] == nil {
+			return nil, p.errorAt(bar, "missing expression before \"|\"")
+		}
+		p.pos++
+		option, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		if option == nil {
+			return nil, p.errorAt(bar, "missing expression after \"|\"")
+		}
+		options = append(options, option)
+	}
+
+	if len(options) == 1 {
+		return first, nil
+	}
+	return Alt{Options: options}, nil
+}
+
+// concat = repeat {repeat}
+// Returns nil for an empty expression.
+func (p *parser) parseConcat() (Regex, error) {
+	items := make([]Regex, 0)
+	for !p.atEnd() && !p.is("|") && !(p.is(")") && p.depth > 0) && !p.atRuleOperator() {
+		item, err := p.parseRepeat()
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+
+	switch len(items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return items[0], nil
+	}
+	return Concat{Items: items}, nil
+}
+
+// repeat = atom {"*" | "+" | "?" | "{" n ["," [m]] "}"}
+func (p *parser) parseRepeat() (Regex, error) {
+	atom, err := p.parseAtom()
+	if err != nil || atom == nil {
+		return atom, err
+	}
+
+	for {
+		switch {
+		case p.is("*"):
+			atom = Star{Expr: atom}
+		case p.is("+"):
+			atom = Plus{Expr: atom}
+		case p.is("?"):
+			atom = Opt{Expr: atom}
+		case p.is("{") && p.nextIsDigit():
+			min, max, err := p.parseRepetitionCount()
+			if err != nil {
+				return nil, err
+			}
+			atom = Repeat{Expr: atom, Min: min, Max: max}
+			continue
+		default:
+			return atom, nil
+		}
+		p.pos++
+	}
+}
+
+// atom = char | escape | quoted | class | group | "{" NAME "}"
+// Returns nil for flags like "(?i)", that match nothing.
+func (p *parser) parseAtom() (Regex, error) {
+	s := p.expresion[p.pos]
+
+	if s.Anchor != NO_ANCHOR {
+		p.pos++
+		return Marker{Anchor: s.Anchor}, nil
+	}
+	if isActionSymbol(s) {
+		p.pos++
+		return Accept{Value: s.Value, Action: s.Action}, nil
+	}
+
+	switch s.Value {
+	case ESCAPE_SYMBOL:
+		symbol, size, err := readEscapeSequence(p.expresion, p.pos)
+		if err != nil {
+			return nil, p.errorAt(p.pos, "%s", err.Error())
+		}
+		p.pos += size
+		return p.symbolToRegex(symbol), nil
+	case QUOTE_SYMBOL:
+		return p.parseQuoted()
+	case "[":
+		return p.parseClass()
+	case "(":
+		return p.parseGroup()
+	case "{":
+		return p.parseRef()
+	case "*", "+", "?":
+		return nil, p.errorAt(p.pos, "nothing to repeat before %q", s.Value)
+	case ")", "]", "}":
+		return nil, p.unexpected()
+	case "/":
+		if p.isRule {
+			return nil, p.errorAt(p.pos, "trailing context \"/\" must not be within parenthesis")
+		}
+	}
+
+	p.pos++
+	return p.literal([]rune(s.Value)[0]), nil
+}
+
+// Parses a quoted string, whose characters are always literals. Ex: "a+b"
+func (p *parser) parseQuoted() (Regex, error) {
+	start := p.pos
+	p.pos++
+
+	items := make([]Regex, 0)
+	for !p.is(QUOTE_SYMBOL) {
+		if p.atEnd() {
+			return nil, p.errorAt(start, "unterminated quoted string")
+		}
+		if p.is(ESCAPE_SYMBOL) {
+			symbol, size, err := readEscapeSequence(p.expresion, p.pos)
+			if err != nil {
+				return nil, p.errorAt(p.pos, "%s", err.Error())
+			}
+			items = append(items, p.symbolToRegex(symbol))
+			p.pos += size
+			continue
+		}
+		items = append(items, p.literal([]rune(p.expresion[p.pos].Value)[0]))
+		p.pos++
+	}
+	p.pos++
+
+	switch len(items) {
+	case 0:
+		return Empty{}, nil
+	case 1:
+		return items[0], nil
+	}
+	return Concat{Items: items}, nil
+}
+
+// Parses a class like "[a-z_]", "[^\n]" or "[[:alpha:]]". Within it every
+// character is a literal except "]", the range "-" and a leading "^" (negation).
+func (p *parser) parseClass() (Regex, error) {
+	start := p.pos
+	p.pos++
+
+	negated := p.is("^")
+	if negated {
+		p.pos++
+	}
+
+	ranges := make([]RuneRange, 0)
+	for !p.is("]") {
+		if p.atEnd() {
+			return nil, p.errorAt(start, "missing \"]\" to close the class")
+		}
+		itemStart := p.pos
+		from, err := p.parseClassItem()
+		if err != nil {
+			return nil, err
+		}
+
+		// A "-" at the end of the class is a literal
+		next, _ := getRawSymbolInfo(p.expresion, p.pos+1)
+		if !p.is(RANGE_SYMBOL) || next.Value == "]" {
+			ranges = append(ranges, from...)
+			continue
+		}
+
+		p.pos++
+		if p.atEnd() {
+			return nil, p.errorAt(start, "missing \"]\" to close the class")
+		}
+		toStart := p.pos
+		to, err := p.parseClassItem()
+		if err != nil {
+			return nil, err
+		}
+		if !isSingleRune(from) || !isSingleRune(to) {
+			return nil, p.errorAt(itemStart, "invalid range, its ends must be single characters")
+		}
+		if from[0].From > to[0].From {
+			return nil, p.errorAt(toStart, "invalid range %c-%c, its end is before its start", from[0].From, to[0].From)
+		}
+		ranges = append(ranges, RuneRange{From: from[0].From, To: to[0].From})
+	}
+	p.pos++
+
+	if len(ranges) == 0 {
+		return nil, p.errorAt(start, "empty class")
+	}
+
+	// Cases are expanded before the negation, so (?i:[^a]) excludes "A" too
+	if p.foldCase {
+		ranges = foldRanges(ranges)
+	}
+	if negated {
+		ranges = negateRanges(ranges)
+	}
+	return Class{Value: p.text(start, p.pos), Ranges: normalizeRanges(ranges)}, nil
+}
+
+// Parses a character, escape sequence or POSIX class within a class.
+func (p *parser) parseClassItem() ([]RuneRange, error) {
+	next, _ := getRawSymbolInfo(p.expresion, p.pos+1)
+	switch {
+	case p.is("[") && next.Value == ":":
+		symbol, size, err := readPosixClass(p.expresion, p.pos)
+		if err != nil {
+			return nil, p.errorAt(p.pos, "%s", err.Error())
+		}
+		p.pos += size
+		return symbol.Ranges, nil
+	case p.is(ESCAPE_SYMBOL):
+		symbol, size, err := readEscapeSequence(p.expresion, p.pos)
+		if err != nil {
+			return nil, p.errorAt(p.pos, "%s", err.Error())
+		}
+		p.pos += size
+		if symbol.Ranges != nil {
+			return symbol.Ranges, nil
+		}
+		r := []rune(symbol.Value)[0]
+		return []RuneRange{{From: r, To: r}}, nil
+	}
+
+	r := []rune(p.expresion[p.pos].Value)[0]
+	p.pos++
+	return []RuneRange{{From: r, To: r}}, nil
+}
+
+// Parses a group "(r)". Groups can also set flags:
+//   - "(?i:r)" and "(?-i:r)" turn case-insensitivity on and off for r.
+//   - "(?i)" and "(?-i)" do the same until the enclosing group ends.
+func (p *parser) parseGroup() (Regex, error) {
+	start := p.pos
+	foldCase := p.foldCase
+
+	if next, _ := getRawSymbolInfo(p.expresion, p.pos+1); next.Value == "?" {
+		fold, scoped, size, err := readGroupFlags(p.expresion, p.pos)
+		if err != nil {
+			return nil, p.errorAt(start, "%s", err.Error())
+		}
+		p.foldCase = fold
+		p.pos += size
+		if !scoped {
+			return nil, nil
+		}
+	} else {
+		p.pos++
+	}
+
+	p.depth++
+	regex, err := p.parseAlt()
+	p.depth--
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(")") {
+		return nil, p.errorAt(start, "missing \")\" to close the group")
+	}
+	p.pos++
+	p.foldCase = foldCase
+
+	if regex == nil {
+		return nil, p.errorAt(start, "empty group")
+	}
+	return regex, nil
+}
+
+// Parses a reference to a named pattern. Ex: {digit}
+func (p *parser) parseRef() (Regex, error) {
+	name, size, err := readBracedSequence(p.expresion, p.pos)
+	if err != nil || !isName(name) {
+		return nil, p.errorAt(p.pos, "invalid reference to a named pattern, expected \"{NAME}\"")
+	}
+	if p.options.Resolve == nil {
+		return nil, p.errorAt(p.pos, "undefined named pattern {%s}", name)
+	}
+	// Errors within the named pattern keep its own position
+	regex, err := p.options.Resolve(name, p.foldCase)
+	if syntaxErr, isSyntaxErr := err.(*SyntaxError); isSyntaxErr {
+		return nil, syntaxErr
+	} else if err != nil {
+		return nil, p.errorAt(p.pos, "%s", err.Error())
+	}
+	p.pos += size
+	return Ref{Name: name, Expr: regex}, nil
+}
+
+// Parses the count of a repetition: "{n}", "{n,}" or "{n,m}".
+// Returns the minimum and maximum times, -1 if unbounded.
+func (p *parser) parseRepetitionCount() (int, int, error) {
+	start := p.pos
+	content, size, err := readBracedSequence(p.expresion, p.pos)
+	if err != nil {
+		return 0, 0, p.errorAt(start, "invalid repetition: %s", err.Error())
+	}
+
+	minText, maxText, hasMax := strings.Cut(content, ",")
+	min, err := strconv.Atoi(minText)
+	if err != nil {
+		return 0, 0, p.errorAt(start, "invalid repetition {%s}", content)
+	}
+	max := min
+	if hasMax && maxText == "" {
+		max = -1
+	} else if hasMax {
+		if max, err = strconv.Atoi(maxText); err != nil || max < min {
+			return 0, 0, p.errorAt(start, "invalid repetition {%s}", content)
+		}
+	}
+	if min > MAX_REPETITIONS || max > MAX_REPETITIONS {
+		return 0, 0, p.errorAt(start, "repetition {%s} is bigger than %d", content, MAX_REPETITIONS)
+	}
+
+	p.pos += size
+	return min, max, nil
+}
+
+// ==================
+// HELPERS
+// ==================
+
+// Returns a literal, expanded to all its cases if case-insensitive.
+func (p *parser) literal(r rune) Regex {
+	if p.foldCase {
+		return foldLiteral(r)
+	}
+	return Literal{Value: r}
+}
+
+// Converts a symbol read from an escape sequence to a literal or class.
+func (p *parser) symbolToRegex(symbol Symbol) Regex {
+	if symbol.Ranges == nil {
+		return p.literal([]rune(symbol.Value)[0])
+	}
+	if p.foldCase {
+		return Class{Value: symbol.Value, Ranges: foldRanges(symbol.Ranges)}
+	}
+	return Class{Value: symbol.Value, Ranges: symbol.Ranges}
+}
+
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.expresion)
+}
+
+// Checks if the symbol at pos is the plain character value (not an action symbol nor anchor).
+func (p *parser) is(value string) bool {
+	s, exist := getRawSymbolInfo(p.expresion, p.pos)
+	return exist && s.Anchor == NO_ANCHOR && !isActionSymbol(s) && s.Value == value
+}
+
+// Checks if the symbol at pos is "/" or the final "$" of a rule, outside groups.
+func (p *parser) atRuleOperator() bool {
+	if !p.isRule || p.depth > 0 {
+		return false
+	}
+	return p.is("/") || (p.is("$") && p.pos == len(p.expresion)-1)
+}
+
+func (p *parser) nextIsDigit() bool {
+	next, _ := getRawSymbolInfo(p.expresion, p.pos+1)
+	return len(next.Value) == 1 && next.Value[0] >= '0' && next.Value[0] <= '9'
+}
+
+// Returns the pattern between 2 positions, as it was written.
+func (p *parser) text(start, end int) string {
+	var sb strings.Builder
+	for _, s := range p.expresion[start:end] {
+		sb.WriteString(s.Value)
+	}
+	return sb.String()
+}
+
+func (p *parser) unexpected() *SyntaxError {
+	return p.errorAt(p.pos, "unexpected %q", p.expresion[p.pos].Value)
+}
+
+func (p *parser) errorAt(pos int, format string, args ...any) *SyntaxError {
+	return &SyntaxError{Message: fmt.Sprintf(format, args...), Column: pos + 1}
+}
+
+// Action symbols, that mark the end of a rule, have more than 1 char and a priority.
+func isActionSymbol(s RawSymbol) bool {
+	return s.Action.Priority > -1 && utf8.RuneCountInString(s.Value) > 1
+}
+
+func isSingleRune(ranges []RuneRange) bool {
+	return len(ranges) == 1 && ranges[0].From == ranges[0].To
+}
+
+// Names of named patterns start with a letter or "_", followed by letters, digits or "_".
+func isName(name string) bool {
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Reads the flags of a group like "(?i:" or "(?-i)" starting at index.
+// Returns if case must be folded, if the flags are scoped to a new group "(?i:r)"
+// (or apply to the rest of the enclosing one "(?i)"), and how many raw symbols were consumed.
+func readGroupFlags(expresion []RawSymbol, index int) (bool, bool, int, error) {
+	flags := ""
+	for j := index + 2; ; j++ {
+		s, exist := getRawSymbolInfo(expresion, j)
+		if !exist {
+			return false, false, 0, fmt.Errorf("unterminated group flags \"(?%s\"", flags)
+		}
+		if s.Value != ":" && s.Value != ")" {
+			flags += s.Value
+			continue
+		}
+
+		switch flags {
+		case "i":
+			return true, s.Value == ":", j - index + 1, nil
+		case "-i":
+			return false, s.Value == ":", j - index + 1, nil
+		}
+		return false, false, 0, fmt.Errorf("unsupported group flags \"(?%s\", only \"i\" and \"-i\" are supported", flags)
+	}
+}
@@ -0,0 +1,232 @@
+package postfix
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		expresion string
+		expected  string
+	}{
+		{`ab|c`, "ab|c"},
+		{`(a|b)*c`, "(a|b)*c"},
+		{`[a-z]+`, "[a-z]+"},
+		{`(ab)?c`, "(ab)?c"},
+		{`a{2}b{1,}c{0,3}`, "a{2}b{1,}c{0,3}"},
+		{`a**`, "(a*)*"},
+		{`((a))`, "a"},
+		{`a.b`, "a.b"},
+	}
+
+	for _, c := range cases {
+		regex, err := Parse(c.expresion, ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		if result := regex.String(); result != c.expected {
+			t.Errorf("%s: expected %q got %q", c.expresion, c.expected, result)
+		}
+	}
+}
+
+func TestParseEscapes(t *testing.T) {
+	cases := []struct {
+		expresion string
+		expected  string
+	}{
+		{`a\tb`, `a\tb`},
+		{`\n\r`, `\n\r`},
+		{`\\`, `\\`},
+		{`\"`, `\"`},
+		{`\x41\x7a`, "Az"},
+		{`\u{3bb}`, "λ"},
+		{`\u{1F600}`, "😀"},
+		{`\+`, `\+`},
+		{`"a b"`, "a b"},
+		{`"a|b*"`, `a\|b\*`},
+		{`"\t\""`, `\t\"`},
+		{`""`, `""`},
+		{`"ab"*`, "(ab)*"},
+		{`["(]`, `["(]`},
+	}
+
+	for _, c := range cases {
+		regex, err := Parse(c.expresion, ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		if result := regex.String(); result != c.expected {
+			t.Errorf("%s: expected %q got %q", c.expresion, c.expected, result)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		expresion string
+		column    int
+	}{
+		{`"abc`, 1},
+		{`a\`, 2},
+		{`\x4`, 1},
+		{`\xZZ`, 1},
+		{`\u41`, 1},
+		{`\u{41`, 1},
+		{`\u{110000}`, 1},
+		{`\u{}`, 1},
+		{`ab|cd)e`, 6},
+		{`(ab`, 1},
+		{`a|`, 2},
+		{`|a`, 1},
+		{`*b`, 1},
+		{`a(+b)`, 3},
+		{`()`, 1},
+		{`[]`, 1},
+		{`[a-`, 1},
+		{`[z-a]`, 4},
+		{`[\d-z]`, 2},
+		{`a{3,2}`, 2},
+		{`a{2000}`, 2},
+		{`{digit}`, 1},
+		{`{1a}`, 1},
+		{``, 1},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(c.expresion, ParseOptions{})
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected a syntax error got %v", c.expresion, err)
+			continue
+		}
+		if syntaxErr.Column != c.column {
+			t.Errorf("%s: expected error at column %d got %v", c.expresion, c.column, err)
+		}
+	}
+
+	err := &SyntaxError{Message: `unexpected ")"`, Column: 7, Rule: 3}
+	if err.Error() != `unexpected ")" at column 7 of rule 3` {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestParseRefs(t *testing.T) {
+	definitions := map[string]string{"letter": "[a-z]", "id": "{letter}({letter}|_)*"}
+	var resolve func(string, bool) (Regex, error)
+	resolve = func(name string, caseInsensitive bool) (Regex, error) {
+		return Parse(definitions[name], ParseOptions{CaseInsensitive: caseInsensitive, Resolve: resolve})
+	}
+
+	regex, err := Parse(`{id}=`, ParseOptions{Resolve: resolve})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regex.String() != "{id}=" {
+		t.Errorf("expected the reference to be kept, got %q", regex.String())
+	}
+	_, postfix, _ := RegexToPostfix(toRawSymbols("([a-z]([a-z]|_)*)="))
+	if result, _ := ToPostfix(regex); !slices.EqualFunc(result, postfix, func(a, b Symbol) bool { return a.Value == b.Value }) {
+		t.Errorf("expected the postfix of the referenced pattern, got %v", result)
+	}
+}
+
+func TestEscapedRangeInClass(t *testing.T) {
+	_, postfix, err := RegexToPostfix(toRawSymbols(`[a\-c]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Expected to be the class {-, a, c}, not the range a-c
+	expected := []RuneRange{{'-', '-'}, {'a', 'a'}, {'c', 'c'}}
+	if len(postfix) != 1 || !slices.Equal(postfix[0].Ranges, expected) {
+		t.Errorf("expected class %v got %v", expected, postfix)
+	}
+}
+
+func TestRegexToPostfix(t *testing.T) {
+	cases := []struct {
+		expresion string
+		expected  string
+	}{
+		{`ab|c`, "ab·c|"},
+		{`a+`, "a+"},
+		{`a?`, "aε|"},
+		{`(ab)+`, "ab·+"},
+		{`a{2,3}`, "aa·aε|·"},
+		{`[a-z]+`, "[a-z]+"},
+	}
+
+	for _, c := range cases {
+		result, _, err := RegexToPostfix(toRawSymbols(c.expresion))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("%s: expected %q got %q", c.expresion, c.expected, result)
+		}
+	}
+}
+
+func TestCaseInsensitiveGroups(t *testing.T) {
+	cases := []struct {
+		expresion string
+		expected  string
+	}{
+		{`(?i:ab)`, "[aA][bB]"},
+		{`(?i:s)`, "[sſS]"},
+		{`a(?i)b(c)`, "a[bB][cC]"},
+		{`((?i)a)b`, "[aA]b"},
+		{`(?i:a(?-i:b)c)`, "[aA]b[cC]"},
+		{`(?i:"if"1)`, "[iI][fF]1"},
+		{`(?i:\x41)`, "[Aa]"},
+	}
+
+	for _, c := range cases {
+		regex, err := Parse(c.expresion, ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		if result := regex.String(); result != c.expected {
+			t.Errorf("%s: expected %q got %q", c.expresion, c.expected, result)
+		}
+	}
+
+	for _, expresion := range []string{`(?x:a)`, `(?i`, `(?:a)`} {
+		if _, err := Parse(expresion, ParseOptions{}); err == nil {
+			t.Errorf("%s: expected an error", expresion)
+		}
+	}
+}
+
+func TestCaseInsensitiveClasses(t *testing.T) {
+	cases := []struct {
+		expresion string
+		expected  []RuneRange
+	}{
+		{`(?i:[a-c])`, []RuneRange{{'A', 'C'}, {'a', 'c'}}},
+		{`(?i:[K])`, []RuneRange{{'K', 'K'}, {'k', 'k'}, {'\u212A', '\u212A'}}},
+		{`(?i:[^a-z])`, negateRanges([]RuneRange{{'A', 'Z'}, {'a', 'z'}, {'ſ', 'ſ'}, {'\u212A', '\u212A'}})},
+	}
+
+	for _, c := range cases {
+		_, postfix, err := RegexToPostfix(toRawSymbols(c.expresion))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.expresion, err)
+			continue
+		}
+		if len(postfix) != 1 || !slices.Equal(postfix[0].Ranges, c.expected) {
+			t.Errorf("%s: expected class %v got %v", c.expresion, c.expected, postfix)
+		}
+	}
+
+	regex, err := Parse("select", ParseOptions{CaseInsensitive: true})
+	if err != nil || regex.String() != "[sſS][eE][lL][eE][cC][tT]" {
+		t.Errorf("expected the option to fold every letter, got %v %v", regex, err)
+	}
+}
@@ -2,33 +2,23 @@ package postfix
 
 import (
 	"strings"
-
-	"github.com/golang-collections/collections/stack"
 )
 
-// Converts a regex string to a slice of symbols in postfix
-//
-// NOTE: Asummes the expresion is balanced in open-close symbols like "()", "[]"
+// Converts a regex to a slice of symbols in postfix, using only primitive operators.
+// Returns the postfix expresion as a string too. Ex: (a|b)?c => ab|ε|c·
 func RegexToPostfix(tokens []RawSymbol) (string, []Symbol, error) {
 
-	// Convert tokens to Symbols, taking into account escaped symbols.
-	symbols, err := convertToSymbols(tokens)
+	// Build the syntax tree, validating the expresion
+	regex, err := ParseRawSymbols(tokens, ParseOptions{})
 	if err != nil {
 		return "", nil, err
 	}
-	// Interchange Especial operators (?, []) to its equivalents
-	primitiveExpresion := convertToPrimitiveOperators(symbols)
 
-	// Add Concatenation Symbols
-	expresionPrepared, err := addConcatenationSymbols(primitiveExpresion)
+	// Walk the tree in postfix order, translating non-primitive operators (?, {n,m})
+	postfixSymbols, err := ToPostfix(regex)
 	if err != nil {
 		return "", nil, err
 	}
-	primitiveExpresion = nil
-
-	// Reorder expresion in postfix notation
-	postfixSymbols := shuntingyard(expresionPrepared)
-	expresionPrepared = nil
 	var sb strings.Builder
 	for _, token := range postfixSymbols {
 		sb.WriteString(token.Value)
@@ -36,42 +26,3 @@ func RegexToPostfix(tokens []RawSymbol) (string, []Symbol, error) {
 
 	return sb.String(), postfixSymbols, nil
 }
-
-func shuntingyard(tokens []Symbol) []Symbol {
-	postfix := make([]Symbol, 0, len(tokens))
-	stack := stack.New()
-
-	for _, token := range tokens {
-		if token.Value == "(" && token.IsOperator {
-			stack.Push(token)
-		} else if token.Value == ")" && token.IsOperator {
-			for {
-				tokenValue, _ := stack.Peek().(Symbol)
-
-				if tokenValue.Value == "(" && tokenValue.IsOperator {
-					break
-				}
-				postfix = append(postfix, stack.Pop().(Symbol))
-			}
-			stack.Pop()
-		} else {
-			for stack.Len() > 0 {
-				peekedChar := stack.Peek().(Symbol)
-
-				if peekedChar.Precedence >= token.Precedence {
-					postfix = append(postfix, stack.Pop().(Symbol))
-				} else {
-					break
-				}
-			}
-			stack.Push(token)
-		}
-
-	}
-
-	for stack.Len() > 0 {
-		postfix = append(postfix, stack.Pop().(Symbol))
-	}
-
-	return postfix
-}
@@ -0,0 +1,311 @@
+package postfix
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file contains the syntax tree of a regex, produced by the parser (parser.go),
+// and its conversion to postfix notation using only primitive operators (·, |, * and +).
+
+// A node of the syntax tree of a regex.
+type Regex interface {
+	// Returns the regex the node stands for, using the same syntax the parser reads.
+	String() string
+}
+
+// A single character. Ex: a
+type Literal struct {
+	Value rune
+}
+
+// Any character within a set of runes. Ex: [a-z], \d, [:alpha:]
+type Class struct {
+	Value  string // How the class was written
+	Ranges []RuneRange
+}
+
+// A sequence of expressions. Ex: abc
+type Concat struct {
+	Items []Regex
+}
+
+// Any of the expressions. Ex: a|b|c
+type Alt struct {
+	Options []Regex
+}
+
+// Zero or more times an expression. Ex: a*
+type Star struct {
+	Expr Regex
+}
+
+// One or more times an expression. Ex: a+
+type Plus struct {
+	Expr Regex
+}
+
+// Zero or one time an expression. Ex: a?
+type Opt struct {
+	Expr Regex
+}
+
+// Between Min and Max times an expression, Max is -1 if unbounded. Ex: a{2,3}, a{2,}
+type Repeat struct {
+	Expr Regex
+	Min  int
+	Max  int
+}
+
+// A reference to a named pattern and the expression it stands for. Ex: {digit}
+type Ref struct {
+	Name string
+	Expr Regex
+}
+
+// The empty string. Ex: ""
+type Empty struct{}
+
+// A position within a rule: its start for "^r" or the start of the trailing context "r/s".
+type Marker struct {
+	Anchor Anchor
+}
+
+// The end of a rule, holding the action to execute when it is recognized.
+type Accept struct {
+	Value  string // Unique symbol of the rule
+	Action Action
+}
+
+func (r Literal) String() string {
+	switch {
+	case strings.ContainsRune(`\|*+?()[]{}"^$/`, r.Value):
+		return ESCAPE_SYMBOL + string(r.Value)
+	case r.Value == '\t':
+		return `\t`
+	case r.Value == '\n':
+		return `\n`
+	case r.Value == '\r':
+		return `\r`
+	case !unicode.IsPrint(r.Value):
+		return fmt.Sprintf(`\u{%x}`, r.Value)
+	}
+	return string(r.Value)
+}
+
+func (r Class) String() string { return r.Value }
+
+func (r Concat) String() string {
+	var sb strings.Builder
+	for _, item := range r.Items {
+		if _, isAlt := item.(Alt); isAlt {
+			sb.WriteString("(" + item.String() + ")")
+			continue
+		}
+		sb.WriteString(item.String())
+	}
+	return sb.String()
+}
+
+func (r Alt) String() string {
+	options := make([]string, len(r.Options))
+	for i, option := range r.Options {
+		options[i] = option.String()
+	}
+	return strings.Join(options, "|")
+}
+
+func (r Star) String() string { return operandString(r.Expr) + "*" }
+func (r Plus) String() string { return operandString(r.Expr) + "+" }
+func (r Opt) String() string  { return operandString(r.Expr) + "?" }
+
+func (r Repeat) String() string {
+	switch {
+	case r.Max == -1:
+		return fmt.Sprintf("%s{%d,}", operandString(r.Expr), r.Min)
+	case r.Min == r.Max:
+		return fmt.Sprintf("%s{%d}", operandString(r.Expr), r.Min)
+	}
+	return fmt.Sprintf("%s{%d,%d}", operandString(r.Expr), r.Min, r.Max)
+}
+
+func (r Ref) String() string    { return "{" + r.Name + "}" }
+func (r Empty) String() string  { return `""` }
+func (r Accept) String() string { return r.Value }
+
+func (r Marker) String() string {
+	if r.Anchor == LINE_START {
+		return "^"
+	}
+	return "/"
+}
+
+// Operands of repetitions made of more than one symbol are wrapped in parenthesis.
+func operandString(r Regex) string {
+	switch r := r.(type) {
+	case Concat:
+		if len(r.Items) == 1 {
+			return operandString(r.Items[0])
+		}
+	case Alt, Star, Plus, Opt, Repeat:
+	default:
+		return r.String()
+	}
+	return "(" + r.String() + ")"
+}
+
+// Returns the length in bytes (UTF-8) of all the strings the regex matches, and false
+// if they have different lengths. Ex: a[0-9] => 2, [aá] => false, a+ => false
+func fixedLength(regex Regex) (int, bool) {
+	switch r := regex.(type) {
+	case Literal:
+		return utf8.RuneLen(r.Value), utf8.RuneLen(r.Value) > 0
+	case Class:
+		length := -1
+		for _, interval := range r.Ranges {
+			from, to := utf8.RuneLen(interval.From), utf8.RuneLen(interval.To)
+			if from <= 0 || from != to || (length != -1 && from != length) {
+				return 0, false
+			}
+			length = from
+		}
+		return length, length > 0
+	case Empty, Marker, Accept:
+		return 0, true
+	case Ref:
+		return fixedLength(r.Expr)
+	case Concat:
+		total := 0
+		for _, item := range r.Items {
+			length, ok := fixedLength(item)
+			if !ok {
+				return 0, false
+			}
+			total += length
+		}
+		return total, true
+	case Alt:
+		length := -1
+		for _, option := range r.Options {
+			optionLength, ok := fixedLength(option)
+			if !ok || (length != -1 && optionLength != length) {
+				return 0, false
+			}
+			length = optionLength
+		}
+		return max(length, 0), true
+	case Star:
+		return repeatedLength(r.Expr)
+	case Plus:
+		return repeatedLength(r.Expr)
+	case Opt:
+		return repeatedLength(r.Expr)
+	case Repeat:
+		length, ok := fixedLength(r.Expr)
+		if !ok || (r.Max != r.Min && length != 0) {
+			return 0, false
+		}
+		return length * r.Min, true
+	}
+	return 0, false
+}
+
+// Repeating or omitting an expression only keeps a fixed length if it matches the empty string alone.
+func repeatedLength(expr Regex) (int, bool) {
+	length, ok := fixedLength(expr)
+	return 0, ok && length == 0
+}
+
+// Converts a regex to postfix notation using only primitive operators:
+//   - r? => rε|
+//   - r{2,3} => rr·rε|·
+//
+// Classes, literals, anchors and action symbols are the operands.
+// Fails if the tree has empty nodes, which the parser never builds.
+func ToPostfix(regex Regex) ([]Symbol, error) {
+	return appendPostfix(make([]Symbol, 0), regex)
+}
+
+func appendPostfix(result []Symbol, regex Regex) ([]Symbol, error) {
+	var err error
+	switch r := regex.(type) {
+	case Literal:
+		return append(result, newLiteralSymbol(string(r.Value))), nil
+	case Class:
+		return append(result, newClassSymbol(r.Value, r.Ranges)), nil
+	case Empty:
+		return append(result, newLiteralSymbol("ε")), nil
+	case Marker:
+		return append(result, Symbol{Value: r.String(), Precedence: 60, Action: Action{Priority: -1}, Anchor: r.Anchor}), nil
+	case Accept:
+		return append(result, Symbol{Value: r.Value, Precedence: 60, Action: r.Action}), nil
+	case Ref:
+		return appendPostfix(result, r.Expr)
+	case Concat:
+		return appendBinary(result, r.Items, CONCAT_SYMBOL)
+	case Alt:
+		return appendBinary(result, r.Options, "|")
+	case Star:
+		if result, err = appendPostfix(result, r.Expr); err != nil {
+			return nil, err
+		}
+		return append(result, OPERATORS["*"]), nil
+	case Plus:
+		if result, err = appendPostfix(result, r.Expr); err != nil {
+			return nil, err
+		}
+		return append(result, OPERATORS["+"]), nil
+	case Opt:
+		return appendPostfix(result, Alt{Options: []Regex{r.Expr, Empty{}}})
+	case Repeat:
+		if r.Min < 0 || (r.Max != -1 && r.Max < r.Min) {
+			return nil, fmt.Errorf("invalid repetition %s", r.String())
+		}
+		return appendPostfix(result, expandRepeat(r))
+	}
+	return nil, fmt.Errorf("invalid regex node %T", regex)
+}
+
+// Appends the operands joined by a binary operator. Ex: abc => ab·c·
+func appendBinary(result []Symbol, operands []Regex, operator string) ([]Symbol, error) {
+	if len(operands) == 0 {
+		return append(result, newLiteralSymbol("ε")), nil
+	}
+	var err error
+	for i, operand := range operands {
+		if result, err = appendPostfix(result, operand); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			result = append(result, OPERATORS[operator])
+		}
+	}
+	return result, nil
+}
+
+// Expresses a repetition with concatenations, optionals and kleene star.
+// The expression is copied, so repetitions are limited to MAX_REPETITIONS.
+// Ex: r{2,} => rrr*, r{1,3} => r(r(r)?)?
+func expandRepeat(r Repeat) Regex {
+	items := make([]Regex, 0, r.Min+1)
+	for range r.Min {
+		items = append(items, r.Expr)
+	}
+
+	if r.Max == -1 {
+		items = append(items, Star{Expr: r.Expr})
+	} else if r.Max > r.Min {
+		var optional Regex = Opt{Expr: r.Expr}
+		for range r.Max - r.Min - 1 {
+			optional = Opt{Expr: Concat{Items: []Regex{r.Expr, optional}}}
+		}
+		items = append(items, optional)
+	}
+
+	if len(items) == 0 {
+		return Empty{}
+	}
+	return Concat{Items: items}
+}
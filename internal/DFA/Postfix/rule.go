@@ -0,0 +1,100 @@
+package postfix
+
+// This file contains the logic for the operators that can only be used at the
+// top level of a rule pattern: line anchors "^r", "r$" and trailing context "r/s".
+
+// The parts of a rule pattern.
+type RulePattern struct {
+	LineStart       bool  // Pattern started with "^"
+	Pattern         Regex // Pattern without anchors nor trailing context
+	TrailingContext Regex // Pattern after "/" ("\n" for "r$"), nil if the rule has none
+}
+
+// Parses a rule pattern into its anchors, main pattern and trailing context.
+// Ex: "^ab/c" => {LineStart: true, Pattern: ab, TrailingContext: c}
+//
+//   - "^" is only an anchor at the start of the pattern.
+//   - "$" is only an anchor at the end of the pattern, and it is the same as "/\n".
+//   - "/" must be at the top level (not within parenthesis) and used once.
+//
+// Escaped, quoted or within a class, they are plain characters.
+func ParseRule(pattern string, options ParseOptions) (RulePattern, error) {
+	rule := RulePattern{}
+	p := newParser(toRawSymbols(pattern), options)
+	p.isRule = true
+
+	if p.is("^") {
+		rule.LineStart = true
+		p.pos++
+	}
+
+	regex, err := p.parseAlt()
+	if err != nil {
+		return rule, err
+	}
+	if regex == nil {
+		return rule, p.errorAt(p.pos, "empty pattern")
+	}
+	rule.Pattern = regex
+
+	if p.is("/") {
+		slash := p.pos
+		p.pos++
+		if rule.TrailingContext, err = p.parseAlt(); err != nil {
+			return rule, err
+		}
+		if p.is("/") {
+			return rule, p.errorAt(p.pos, "rule has more than one trailing context \"/\"")
+		}
+		if rule.TrailingContext == nil && !p.is("$") {
+			return rule, p.errorAt(slash, "empty trailing context after \"/\"")
+		}
+	}
+
+	if p.is("$") {
+		p.pos++
+		lineEnd := Literal{Value: '\n'}
+		if rule.TrailingContext == nil {
+			rule.TrailingContext = lineEnd
+		} else {
+			rule.TrailingContext = Concat{Items: []Regex{rule.TrailingContext, lineEnd}}
+		}
+	}
+
+	if !p.atEnd() {
+		return rule, p.unexpected()
+	}
+	return rule, nil
+}
+
+// Returns the length in bytes (UTF-8) of the strings the trailing context matches,
+// and false if the rule has none or they have different lengths. Ex: "r/ab" => 2
+func (rule RulePattern) TrailingLength() (int, bool) {
+	if rule.TrailingContext == nil {
+		return 0, false
+	}
+	return fixedLength(rule.TrailingContext)
+}
+
+// Joins the parts of a rule with the special symbols that mark its positions,
+// ending with the symbol of its action: ^pattern/(trailing context)accept
+// The trailing context of the action is set from the rule.
+func (rule RulePattern) Regex(accept Accept) Regex {
+	accept.Action.TrailingContext = rule.TrailingContext != nil
+	if accept.Action.TrailingContext {
+		length, fixed := rule.TrailingLength()
+		if !fixed {
+			length = -1
+		}
+		accept.Action.TrailingLength = length
+	}
+	items := make([]Regex, 0, 5)
+	if rule.LineStart {
+		items = append(items, Marker{Anchor: LINE_START})
+	}
+	items = append(items, rule.Pattern)
+	if rule.TrailingContext != nil {
+		items = append(items, Marker{Anchor: TRAILING_CONTEXT}, rule.TrailingContext)
+	}
+	return Concat{Items: append(items, accept)}
+}
@@ -0,0 +1,78 @@
+package postfix
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		pattern         string
+		lineStart       bool
+		expected        string
+		trailingContext string
+	}{
+		{`abc`, false, `abc`, ``},
+		{`^abc`, true, `abc`, ``},
+		{`abc$`, false, `abc`, `\n`},
+		{`ab/cd`, false, `ab`, `cd`},
+		{`^ab/c$`, true, `ab`, `c\n`},
+		{`a^b$c`, false, `a\^b\$c`, ``},
+		{`a\/b\$`, false, `a\/b\$`, ``},
+		{`"a/b$"`, false, `a\/b\$`, ``},
+		{`[/$]`, false, `[/$]`, ``},
+		{`[[:alpha:]/]x`, false, `[[:alpha:]/]x`, ``},
+		{`(a$)`, false, `a\$`, ``},
+	}
+
+	for _, c := range cases {
+		result, err := ParseRule(c.pattern, ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.pattern, err)
+			continue
+		}
+		trailingContext := ""
+		if result.TrailingContext != nil {
+			trailingContext = result.TrailingContext.String()
+		}
+		if result.LineStart != c.lineStart || result.Pattern.String() != c.expected || trailingContext != c.trailingContext {
+			t.Errorf("%s: expected (%v, %s, %s) got (%v, %s, %s)", c.pattern,
+				c.lineStart, c.expected, c.trailingContext, result.LineStart, result.Pattern, trailingContext)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, pattern := range []string{`a/b/c`, `(a/b)`, `a/`, `/a`, `^`, `$`, `a)`} {
+		if _, err := ParseRule(pattern, ParseOptions{}); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
+
+func TestTrailingLength(t *testing.T) {
+	cases := []struct {
+		pattern string
+		length  int
+		fixed   bool
+	}{
+		{`ab`, 0, false},
+		{`a/b`, 1, true},
+		{`a$`, 1, true},
+		{`a/ñ[0-9]{3}`, 5, true},
+		{`a/(bc|de)x?""`, 0, false},
+		{`a/(bc|de)`, 2, true},
+		{`a/b+`, 0, false},
+		{`a/[aá]`, 0, false},
+		{`a/(?i:k)`, 0, false}, // The Kelvin sign K is 3 bytes
+	}
+
+	for _, c := range cases {
+		rule, err := ParseRule(c.pattern, ParseOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", c.pattern, err)
+		}
+		if length, fixed := rule.TrailingLength(); length != c.length || fixed != c.fixed {
+			t.Errorf("%s: expected (%d, %v), got (%d, %v)", c.pattern, c.length, c.fixed, length, fixed)
+		}
+	}
+}
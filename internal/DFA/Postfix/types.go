@@ -1,15 +1,27 @@
 package postfix
 
 type Action struct {
-	Priority int // -1 if token does not have an action
-	Code     string
+	Priority        int // -1 if token does not have an action
+	Code            string
+	TrailingContext bool // If the rule has a trailing context "r/s", so the lexeme ends before s
+	TrailingLength  int  // Bytes (in UTF-8) of s for rules with trailing context, -1 if its length is not fixed
 }
 
+// Special symbols that mark a position within a rule instead of matching characters.
+type Anchor int
+
+const (
+	NO_ANCHOR        Anchor = iota
+	LINE_START              // "^r", r only matches at the start of a line
+	TRAILING_CONTEXT        // "r/s", r only matches if followed by s
+)
+
 // Contains raw data of a symbol/rune, acts like as a an analugous of the rune type
 // but containing action data, for special processing.
 type RawSymbol struct {
 	Value  string
 	Action Action
+	Anchor Anchor
 }
 
 // Representation of a string that can be used for processing regex patterns.
@@ -26,6 +38,18 @@ type Symbol struct {
 
 	// Number of Operands
 	Operands int
+
+	// For classes, the set of runes it matches. Nil for single characters.
+	Ranges []RuneRange
+
+	// For anchors, the kind of position it marks.
+	Anchor Anchor
+}
+
+// Inclusive interval of runes. Ex: [a-z] => {From: 'a', To: 'z'}
+type RuneRange struct {
+	From rune
+	To   rune
 }
 
 func (s *Symbol) String() string {
@@ -33,17 +57,14 @@ func (s *Symbol) String() string {
 }
 
 const ESCAPE_SYMBOL string = "\\"
+const QUOTE_SYMBOL string = "\""
+const RANGE_SYMBOL string = "-"
 const CONCAT_SYMBOL string = "·"
 
+// Operators of the postfix notation, the other ones are translated to these.
 var OPERATORS = map[string]Symbol{
-	")": {Value: ")", Precedence: 10, IsOperator: true, Operands: 1},
-	"(": {Value: "(", Precedence: 10, IsOperator: true, Operands: 0},
-	"]": {Value: "]", Precedence: 10, IsOperator: true, Operands: 1},
-	"[": {Value: "[", Precedence: 10, IsOperator: true, Operands: 0},
 	"|": {Value: "|", Precedence: 20, IsOperator: true, Operands: 2},
 	"·": {Value: "·", Precedence: 30, IsOperator: true, Operands: 2},
-	"?": {Value: "?", Precedence: 40, IsOperator: true, Operands: 1},
 	"*": {Value: "*", Precedence: 40, IsOperator: true, Operands: 1},
 	"+": {Value: "+", Precedence: 40, IsOperator: true, Operands: 1},
-	"^": {Value: "^", Precedence: 50, IsOperator: true, Operands: 2},
 }
@@ -1,6 +1,10 @@
 package dfa
 
-import postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+import (
+	"fmt"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
 
 /*
 BuildAST construye un AST a partir de una lista de símbolos en notación postfix.
@@ -10,25 +14,33 @@ Parámetros:
 Retorno:
   - Un nodo (Node) que representa la raíz del AST construido a partir de la expresión postfix.
 
-Panic:
- 1. Si la expresión postfix es inválida, no está balanceada o en el stack hay menos símbolos de los que necesita un operador.
+Error:
+ 1. Si la expresión postfix es inválida, en el stack hay menos símbolos de los que necesita un operador.
+    El error indica el operador y la regla (por su símbolo de acción) a la que pertenece.
  2. Resultado del stack final no es un solo nodo (tal que la cantidad de operadores relacionados es incorrecta y faltan o sobran símbolos).
 */
-func BuildAST(postfixSymbols []postfix.Symbol) node {
+func BuildAST(postfixSymbols []postfix.Symbol) (node, error) {
 	var stack []node
 
+	if len(postfixSymbols) == 0 {
+		return node{}, fmt.Errorf("expresión postfix inválida: está vacía")
+	}
+
 	// Recorrer toda la lista de símbolos en notación postfix
 	for i, symbol := range postfixSymbols {
 
-		// fmt.Printf("Value: %s isOperator %v numOperators: %d \n", symbol.Value, symbol.IsOperator, symbol.Operands)
 		// Verifica si el símbolo es un operador
 		if symbol.IsOperator {
 
 			// Obtener la cantidad de símbolos que necesita el operador
 			operandCount := symbol.Operands
+			if operandCount < 1 || operandCount > 2 {
+				return node{}, fmt.Errorf("expresión postfix inválida: el operador %q %s necesita %d operandos",
+					symbol.Value, describeRule(postfixSymbols, i), operandCount)
+			}
 			if len(stack) < operandCount {
-				//log.Panic().Msg("Expresión postfix inválida: falta operando")
-				panic("Expresión postfix inválida: falta operando")
+				return node{}, fmt.Errorf("expresión postfix inválida: al operador %q %s le falta un operando",
+					symbol.Value, describeRule(postfixSymbols, i))
 			}
 
 			// Añadir los símbolos que necesita el operador a operands
@@ -42,9 +54,10 @@ func BuildAST(postfixSymbols []postfix.Symbol) node {
 			for i, j := 0, len(operands)-1; i < j; i, j = i+1, j-1 {
 				operands[i], operands[j] = operands[j], operands[i]
 			}
-			// Crear un nodo operador con los operandos
+			// Crear un nodo operador con los operandos. Su Id es negativo y distinto
+			// al de las hojas, incluyendo ε (-1), para no compartir fila en la tabla de posiciones.
 			node := node{
-				Id:         -i,
+				Id:         -(i + 2),
 				Value:      symbol.Value,
 				Operands:   symbol.Operands,
 				Children:   operands,
@@ -59,15 +72,21 @@ func BuildAST(postfixSymbols []postfix.Symbol) node {
 					Value:      symbol.Value,
 					IsOperator: false}
 				stack = append(stack, node)
+			} else if symbol.Value == "" {
+				return node{}, fmt.Errorf("expresión postfix inválida: símbolo vacío %s", describeRule(postfixSymbols, i))
 			} else {
 				node := node{
 					Id:         i,
 					Value:      symbol.Value,
 					IsOperator: false,
 					Action: Action{
-						Priority: symbol.Action.Priority,
-						Code:     symbol.Action.Code,
+						Priority:        symbol.Action.Priority,
+						Code:            symbol.Action.Code,
+						TrailingContext: symbol.Action.TrailingContext,
+						TrailingLength:  symbol.Action.TrailingLength,
 					},
+					Ranges: symbolRanges(symbol),
+					Anchor: symbol.Anchor,
 				}
 				stack = append(stack, node)
 			}
@@ -75,8 +94,30 @@ func BuildAST(postfixSymbols []postfix.Symbol) node {
 	}
 
 	if len(stack) != 1 {
-		//log.Panic().Msg("Expresión postfix inválida: el resultado final no es un solo nodo")
-		panic("Expresión postfix inválida: falta operando")
+		return node{}, fmt.Errorf("expresión postfix inválida: sobran %d operandos sin operador", len(stack)-1)
+	}
+	return stack[0], nil
+}
+
+// Describe la regla a la que pertenece el símbolo en la posición index: la del
+// primer símbolo de acción que le sigue, ya que cada regla termina con el suyo.
+func describeRule(postfixSymbols []postfix.Symbol, index int) string {
+	for _, symbol := range postfixSymbols[index:] {
+		if !symbol.IsOperator && symbol.Action.Priority > -1 {
+			return fmt.Sprintf("de la regla %d", symbol.Action.Priority+1)
+		}
+	}
+	return fmt.Sprintf("en la posición %d", index)
+}
+
+// Returns the runes a leaf symbol matches. Action symbols (priority > -1) and anchors match no rune.
+func symbolRanges(symbol postfix.Symbol) []postfix.RuneRange {
+	if symbol.Action.Priority > -1 || symbol.Anchor != postfix.NO_ANCHOR {
+		return nil
+	}
+	if symbol.Ranges != nil {
+		return symbol.Ranges
 	}
-	return stack[0]
+	r := []rune(symbol.Value)[0]
+	return []postfix.RuneRange{{From: r, To: r}}
 }
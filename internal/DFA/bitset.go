@@ -0,0 +1,68 @@
+package dfa
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Set of non negative integers (positions or NFA states), one bit for each.
+// Used for followpos and the sets of the DFA states, since they are compared and
+// joined many times while building the DFA.
+type bitset []uint64
+
+// Creates an empty set able to hold the numbers from 0 to size-1.
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+// Creates a set with the given numbers, all of them lower than size.
+func bitsetOf(size int, items []int) bitset {
+	set := newBitset(size)
+	for _, item := range items {
+		set.add(item)
+	}
+	return set
+}
+
+func (s bitset) add(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+func (s bitset) has(i int) bool {
+	return i/64 < len(s) && s[i/64]&(1<<(i%64)) != 0
+}
+
+// Adds all numbers of other to the set, other can not be bigger. Returns if the set changed.
+func (s bitset) union(other bitset) bool {
+	changed := false
+	for i, word := range other {
+		if s[i]|word != s[i] {
+			s[i] |= word
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Returns the numbers of the set in increasing order.
+func (s bitset) items() []int {
+	result := make([]int, 0)
+	for i, word := range s {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			result = append(result, i*64+bit)
+			word &= word - 1
+		}
+	}
+	return result
+}
+
+// Returns a string that identifies the set, so it can be used as a map key.
+// Sets created with the same size have the same key only if they have the same numbers.
+func (s bitset) key() string {
+	key := make([]byte, 0, len(s)*8)
+	for _, word := range s {
+		key = binary.LittleEndian.AppendUint64(key, word)
+	}
+	return string(key)
+}
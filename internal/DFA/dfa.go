@@ -2,42 +2,102 @@ package dfa
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"slices"
 	"sort"
+	"time"
+	"unicode/utf8"
 
 	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
 )
 
-// Generates a Deterministic finite automate for language recogniation based on sequence of raw symbols
+// Generates a Deterministic finite automate for language recogniation based on the syntax tree of a regex
 //
 // Parameters
-// - rawExpresion: a list of symbols that represents a regrex expresion.
-// Distinguish between to types of symbols:
-// - Actionable symbol: Metacharacter, that contains an action to execute when a pattern is recognized.
-// - Common Symbol : just represents a plain character
+// - expresion: the regex of all rules, as built by the parser.
+// Distinguish between to types of leaves:
+// - Actionable symbol (postfix.Accept): contains an action to execute when a pattern is recognized.
+// - Common Symbol : a plain character or class
+// - construction: the algorithm used, both produce DFAs that recognize the same language.
 //
-// Returns the DFA built, the number of final symbols (used for absortion state removal)
-func NewDFA(rawExpresion []postfix.RawSymbol, showLogs bool) (*DFA, int, error) {
+// - logger: receives the time each phase ("ast" and "dfa") takes, and its details at debug level. Nil to log nothing.
+//
+// Returns the DFA built, the number of symbols states can move with (used for absortion state removal)
+func NewDFA(expresion postfix.Regex, construction Construction, logger *slog.Logger) (*DFA, int, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	start := time.Now()
 
-	// Convert Raw Symbols to Symbols on postfix
-	_, postfixExpr, err := postfix.RegexToPostfix(rawExpresion)
+	// Convert the syntax tree to Symbols on postfix
+	postfixExpr, err := postfix.ToPostfix(expresion)
 	if err != nil {
 		return nil, 0, err
 	}
+	logger.Debug("postfix", "expression", PostfixString(postfixExpr))
 
-	if showLogs {
-		fmt.Print("\n\n")
-		for _, a := range postfixExpr {
-			fmt.Print(a.Value)
-		}
-		fmt.Print("\n\n")
+	// Build Abstract Syntax Tree, it also checks the postfix expresion is valid.
+	ast, err := BuildAST(postfixExpr)
+	if err != nil {
+		return nil, 0, err
 	}
+	logger.Info("phase", "phase", "ast", "duration", time.Since(start), "positions", len(postfixExpr)+1)
+	start = time.Now()
+
+	finalSymbols := findFinalSymbols(postfixExpr)
+	anchorRules := findAnchorRules(postfixExpr)
+
+	var intermediateStates []*nodeSet
+	var lineStartState *nodeSet
+	switch construction {
+	case DIRECT_CONSTRUCTION:
+		intermediateStates, lineStartState = directConstruction(ast, len(postfixExpr), finalSymbols, anchorRules)
+	case THOMPSON_CONSTRUCTION:
+		nfaStart, numNFAStates := buildNFA(postfixExpr)
+		intermediateStates, lineStartState = subsetConstruction(finalSymbols, nfaStart, numNFAStates, anchorRules)
+	default:
+		return nil, 0, fmt.Errorf("unknown DFA construction %q", construction)
+	}
+	logger.Debug("intermediate states", "construction", construction, "states", len(intermediateStates), "alphabet", len(finalSymbols))
+
+	// Build DFA
+	dfa := convertToDFA(intermediateStates, finalSymbols, lineStartState, findRules(postfixExpr))
+	logger.Info("phase", "phase", "dfa", "duration", time.Since(start), "construction", construction, "states", len(dfa.States))
+
+	return dfa, numIntervalSymbols(finalSymbols), nil
+}
+
+// Returns the construction with the given name. Ex: "direct", "thompson"
+func ParseConstruction(name string) (Construction, error) {
+	switch construction := Construction(name); construction {
+	case DIRECT_CONSTRUCTION, THOMPSON_CONSTRUCTION:
+		return construction, nil
+	}
+	return "", fmt.Errorf("unknown DFA construction %q, use %q or %q", name, DIRECT_CONSTRUCTION, THOMPSON_CONSTRUCTION)
+}
+
+// Computes the DFA states with the direct method, using the followpos of the positions
+// of the syntax tree. numPositions is the length of the postfix expresion the tree was built from.
+func directConstruction(
+	ast node,
+	numPositions int,
+	finalSymbols []alphabetSymbol,
+	anchorRules map[int]int) ([]*nodeSet, *nodeSet) {
+
+	// Generate DFA with direct method
+	positionTable, firstPost := buildPositionTable(ast, numPositions)
 
-	// Build Abstract Syntax Tree
+	// Simplify DFA
+	return simplifyStates(finalSymbols, firstPost, positionTable, anchorRules)
+}
 
-	ast := BuildAST(postfixExpr)
-	RenderAST(ast, "./diagram/tree.png")
+// Computes the nullable, firstpos, lastpos and followpos of the positions of the
+// syntax tree, after adding the end of the expresion "#". Returns the table and the
+// firstpos of the root.
+func buildPositionTable(ast node, numPositions int) ([]positionTableRow, []int) {
 	centinelNode := node{
-		Id:         len(postfixExpr),
+		Id:         numPositions,
 		Value:      "#",
 		Operands:   2,
 		Children:   []node{ast},
@@ -47,59 +107,175 @@ func NewDFA(rawExpresion []postfix.RawSymbol, showLogs bool) (*DFA, int, error)
 	}
 
 	rootNode := node{
-		Id:         -(len(postfixExpr) + 1),
+		Id:         -(numPositions + 2),
 		Value:      "·",
 		Operands:   2,
 		Children:   []node{ast, centinelNode},
 		IsOperator: true}
 
-	// Generate DFA with direct method
-	finalSymbols := findFinalSymbols(postfixExpr)
-	positionTable := make(map[int]positionTableRow)
+	positionTable := make([]positionTableRow, numPositions+1)
 	_, firstPost, _ := getNodePosition(&rootNode, positionTable)
-	setFollowPos(&rootNode, positionTable)
+	return positionTable, firstPost
+}
 
-	// Simplify DFA
-	intermediateStates := simplifyStates(finalSymbols, firstPost, positionTable)
-	if showLogs {
-		printPositionTable(positionTable)
-		printStateSetTable(intermediateStates, finalSymbols)
+// Returns the position table of the direct construction for expresion, one row for
+// each character, class, anchor and action symbol, the last one is the end "#".
+func PositionTable(expresion postfix.Regex) ([]PositionRow, error) {
+	postfixExpr, err := postfix.ToPostfix(expresion)
+	if err != nil {
+		return nil, err
 	}
+	ast, err := BuildAST(postfixExpr)
+	if err != nil {
+		return nil, err
+	}
+	positionTable, _ := buildPositionTable(ast, len(postfixExpr))
 
-	// Build DFA
-	dfa := convertToDFA(intermediateStates, finalSymbols)
+	rows := make([]PositionRow, 0, len(positionTable))
+	// The positions of a rule are the ones before its action symbol
+	rule := -1
+	for position := len(positionTable) - 1; position >= 0; position-- {
+		row := positionTable[position]
+		// Operators and ε have no position
+		if row.token == "" {
+			continue
+		}
+		if row.action.Priority >= 0 {
+			rule = row.action.Priority
+		}
+		rows = append(rows, PositionRow{
+			Position:  position,
+			Symbol:    row.token,
+			Rule:      rule,
+			Nullable:  row.nullable,
+			FirstPos:  row.firstPos,
+			LastPos:   row.lastPos,
+			FollowPos: row.followPos.items(),
+		})
+	}
+	slices.Reverse(rows)
+	return rows, nil
+}
+
+// Return the alphabet of an expresion: all different action symbols and the disjoint
+// intervals of runes the characters and classes (not operators) can be split in.
+// Intervals are sorted, followed by the action symbols in the order of its rules.
+func findFinalSymbols(expresion []postfix.Symbol) []alphabetSymbol {
+	actionSymbols := make([]alphabetSymbol, 0)
+	seenActions := make(map[string]bool)
+	// Points where an interval starts (+1) or ends (-1)
+	bounds := make(map[rune]int)
+
+	for _, symbol := range expresion {
+		if symbol.IsOperator || symbol.Value == "ε" || symbol.Anchor != postfix.NO_ANCHOR {
+			continue
+		}
+		ranges := symbolRanges(symbol)
+		if ranges == nil {
+			if !seenActions[symbol.Value] {
+				seenActions[symbol.Value] = true
+				actionSymbols = append(actionSymbols, alphabetSymbol{token: symbol.Value, isAction: true})
+			}
+			continue
+		}
+		for _, r := range ranges {
+			bounds[r.From]++
+			bounds[r.To+1]--
+		}
+	}
+
+	points := make([]rune, 0, len(bounds))
+	for point := range bounds {
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+
+	// Sweep the points, any interval between 2 points covered by a range is a symbol.
+	symbols := make([]alphabetSymbol, 0, len(points)+len(actionSymbols))
+	covering := 0
+	for i := 0; i < len(points)-1; i++ {
+		covering += bounds[points[i]]
+		if covering > 0 {
+			symbols = append(symbols, newIntervalSymbol(points[i], points[i+1]-1))
+		}
+	}
 
-	return dfa, len(finalSymbols), nil
+	return append(symbols, actionSymbols...)
 }
 
-// Return a list with all different final symbols (Not operators) from an expresion.
-func findFinalSymbols(expresion []postfix.Symbol) []string {
-	symbolsSet := make(map[postfix.Symbol]bool)
+// Returns the priority of the rule each anchor (by its position) belongs to.
+// The symbols of a rule always come before its action symbol in the expresion.
+func findAnchorRules(expresion []postfix.Symbol) map[int]int {
+	anchorRules := make(map[int]int)
+	rule := -1
+	for i := len(expresion) - 1; i >= 0; i-- {
+		symbol := expresion[i]
+		if !symbol.IsOperator && symbol.Action.Priority > -1 {
+			rule = symbol.Action.Priority
+		} else if symbol.Anchor != postfix.NO_ANCHOR {
+			anchorRules[i] = rule
+		}
+	}
+	return anchorRules
+}
 
+// Returns the actions of all rules, indexed by its priority.
+func findRules(expresion []postfix.Symbol) []Action {
+	rules := make([]Action, 0)
 	for _, symbol := range expresion {
-		if symbol.IsOperator || symbol.Value == "ε" {
+		if symbol.IsOperator || symbol.Action.Priority < 0 {
 			continue
 		}
-		if _, exist := symbolsSet[symbol]; !exist {
-			symbolsSet[symbol] = true
+		for len(rules) <= symbol.Action.Priority {
+			rules = append(rules, Action{Priority: len(rules)})
+		}
+		rules[symbol.Action.Priority] = Action{
+			Code:            symbol.Action.Code,
+			Priority:        symbol.Action.Priority,
+			TrailingContext: symbol.Action.TrailingContext,
+			TrailingLength:  symbol.Action.TrailingLength,
 		}
 	}
+	return rules
+}
 
-	symbols := make([]string, 0, len(symbolsSet))
+// Returns how many symbols of the alphabet are intervals of runes, instead of action symbols.
+func numIntervalSymbols(alphabet []alphabetSymbol) int {
+	count := 0
+	for _, symbol := range alphabet {
+		if !symbol.isAction {
+			count++
+		}
+	}
+	return count
+}
 
-	for symbol := range symbolsSet {
-		symbols = append(symbols, symbol.Value)
+// Creates an alphabet symbol for the interval [from, to]. Single runes are
+// represented by themselves, so they can be looked up as is. Ex: "a", "0-9"
+func newIntervalSymbol(from, to rune) alphabetSymbol {
+	token := string(from)
+	if from != to {
+		token = fmt.Sprintf("%c-%c", from, to)
 	}
+	return alphabetSymbol{token: token, from: from, to: to}
+}
 
-	return symbols
+// Returns the tokens of the alphabet symbols.
+func alphabetTokens(alphabet []alphabetSymbol) []string {
+	tokens := make([]string, len(alphabet))
+	for i, symbol := range alphabet {
+		tokens[i] = symbol.token
+	}
+	return tokens
 }
 
 //==================================
 // ISNULLABLE, FIRSTPOST, LASTPOST
 //==================================
 
-// Fills a position table with firstpos and lastpos properties for all its nodes
-// its does so by calling itself recursively.
+// Fills a position table with firstpos and lastpos properties for all its leaves
+// its does so by calling itself recursively. The followpos of the leaves is computed
+// as well, by the concatenation and repetition operators.
 // Returns:
 //
 // - isNullable(bool) : If this node is nullable.
@@ -107,7 +283,9 @@ func findFinalSymbols(expresion []postfix.Symbol) []string {
 // - fistPos([]int) : Set of nodes ID's that comprenhend its firstpos
 //
 // - lastPos([]int): Set of nodes ID's that comprehend its lastpos
-func getNodePosition(root *node, positionTable map[int]positionTableRow) (bool, []int, []int) {
+//
+// Since the children of a node are different subtrees, their positions never repeat.
+func getNodePosition(root *node, positionTable []positionTableRow) (bool, []int, []int) {
 	// If Node is an operator with 2 operands
 	if root.IsOperator && root.Operands == 2 {
 		if root.Value == "·" {
@@ -120,131 +298,98 @@ func getNodePosition(root *node, positionTable map[int]positionTableRow) (bool,
 	if root.IsOperator && root.Operands == 1 && root.Value == "*" {
 		return positionKleenOperator(root, positionTable)
 	}
+	// If Node is + operator
+	if root.IsOperator && root.Operands == 1 && root.Value == "+" {
+		return positionPositiveOperator(root, positionTable)
+	}
 
-	// Else if node is empty string
+	// Else if node is empty string, it has no position
 	if root.Value == "ε" {
-		isNullable := true
-		firstPos := make([]int, 0)
-		lastPos := make([]int, 0)
-
-		positionTable[root.Id] = positionTableRow{
-			token:    root.Value,
-			nullable: isNullable,
-			firstPos: firstPos,
-			lastPos:  lastPos,
-			action:   Action{Priority: -1},
-		}
-		return isNullable, firstPos, lastPos
+		return true, []int{}, []int{}
 	}
 
-	isNullable := false
-	firstPos := []int{root.Id}
-	lastPos := []int{root.Id}
-	isFinal := root.IsFinal
-
 	positionTable[root.Id] = positionTableRow{
-		token:    root.Value,
-		nullable: isNullable,
-		firstPos: firstPos,
-		lastPos:  lastPos,
-		isFinal:  isFinal,
-		action:   root.Action,
+		token:     root.Value,
+		ranges:    root.Ranges,
+		anchor:    root.Anchor,
+		nullable:  false,
+		firstPos:  []int{root.Id},
+		lastPos:   []int{root.Id},
+		followPos: newBitset(len(positionTable)),
+		isFinal:   root.IsFinal,
+		action:    root.Action,
 	}
 	// Then, this means is a leaf of a Final Symbol
 	return false, []int{root.Id}, []int{root.Id}
 }
 
-func positionKleenOperator(n *node, positionTable map[int]positionTableRow) (bool, []int, []int) {
-	_, firstPos1, lastPos1 := getNodePosition(&n.Children[0], positionTable)
-	isNullable := true
-	firstPos := firstPos1
-	lastPos := lastPos1
-	positionTable[n.Id] = positionTableRow{
-		token:    n.Value,
-		nullable: isNullable,
-		firstPos: firstPos,
-		lastPos:  lastPos,
-	}
+// The firstpos of a repetition follow its lastpos.
+func positionKleenOperator(n *node, positionTable []positionTableRow) (bool, []int, []int) {
+	_, firstPos, lastPos := getNodePosition(&n.Children[0], positionTable)
+	setFollowPos(lastPos, firstPos, positionTable)
+	return true, firstPos, lastPos
+}
+
+// Same as kleen operator, but it is only nullable if its child is.
+func positionPositiveOperator(n *node, positionTable []positionTableRow) (bool, []int, []int) {
+	isNullable, firstPos, lastPos := getNodePosition(&n.Children[0], positionTable)
+	setFollowPos(lastPos, firstPos, positionTable)
 	return isNullable, firstPos, lastPos
 }
 
-func positionOrOperator(n *node, positionTable map[int]positionTableRow) (bool, []int, []int) {
+func positionOrOperator(n *node, positionTable []positionTableRow) (bool, []int, []int) {
 	nullable1, firstPos1, lastPos1 := getNodePosition(&n.Children[0], positionTable)
 	nullable2, firstPos2, lastPos2 := getNodePosition(&n.Children[1], positionTable)
 	isNullable := nullable1 || nullable2
-	firstPos := getNumberSet(firstPos1, firstPos2)
-	lastPos := getNumberSet(lastPos1, lastPos2)
-	positionTable[n.Id] = positionTableRow{
-		token:    n.Value,
-		nullable: isNullable,
-		firstPos: firstPos,
-		lastPos:  lastPos,
-	}
+	firstPos := joinPositions(firstPos1, firstPos2)
+	lastPos := joinPositions(lastPos1, lastPos2)
 	return isNullable, firstPos, lastPos
 }
 
-func positionConcatenationOperator(n *node, positionTable map[int]positionTableRow) (bool, []int, []int) {
+// The firstpos of the second child follow the lastpos of the first one.
+func positionConcatenationOperator(n *node, positionTable []positionTableRow) (bool, []int, []int) {
 	nullable1, firstPos1, lastPos1 := getNodePosition(&n.Children[0], positionTable)
 	nullable2, firstPos2, lastPos2 := getNodePosition(&n.Children[1], positionTable)
+	setFollowPos(lastPos1, firstPos2, positionTable)
+
 	var firstPos []int
 	var lastPos []int
 
 	isNullable := nullable1 && nullable2
 
 	if nullable1 {
-		firstPos = getNumberSet(firstPos1, firstPos2)
+		firstPos = joinPositions(firstPos1, firstPos2)
 	} else {
 		firstPos = firstPos1
 	}
 
 	if nullable2 {
-		lastPos = getNumberSet(lastPos1, lastPos2)
+		lastPos = joinPositions(lastPos1, lastPos2)
 	} else {
 		lastPos = lastPos2
 	}
 
-	positionTable[n.Id] = positionTableRow{
-		token:    n.Value,
-		nullable: isNullable,
-		firstPos: firstPos,
-		lastPos:  lastPos,
-	}
 	return isNullable, firstPos, lastPos
 }
 
+// Returns the positions of both a and b, which have none in common.
+func joinPositions(a, b []int) []int {
+	result := make([]int, 0, len(a)+len(b))
+	return append(append(result, a...), b...)
+}
+
 //============================
 // FOLLOWPOST
 //============================
 
-// Computes the followpost for each row in-place in a position table.
-func setFollowPos(root *node, positionTable map[int]positionTableRow) {
-	// calculate follow post of children
-	if !root.IsOperator {
+// Adds the positions of follow to the followpos of each position in positions.
+func setFollowPos(positions, follow []int, positionTable []positionTableRow) {
+	if len(follow) == 0 {
 		return
 	}
-
-	if root.Value == "·" {
-		c1 := positionTable[root.Children[0].Id]
-		c2 := positionTable[root.Children[1].Id]
-		for _, n := range c1.lastPos {
-			node := positionTable[n]
-			//fmt.Printf("\tC1: %d C2: %v\n", n, c2.firstPos)
-			node.followPos = getNumberSet(node.followPos, c2.firstPos)
-			positionTable[n] = node
-		}
-	}
-
-	if root.Value == "*" {
-		c := positionTable[root.Id]
-		for _, n := range c.lastPos {
-			node := positionTable[n]
-			node.followPos = getNumberSet(node.followPos, c.firstPos)
-			positionTable[n] = node
-		}
-	}
-
-	for _, child := range root.Children {
-		setFollowPos(&child, positionTable)
+	followSet := bitsetOf(len(positionTable), follow)
+	for _, position := range positions {
+		positionTable[position].followPos.union(followSet)
 	}
 }
 
@@ -254,136 +399,231 @@ func setFollowPos(root *node, positionTable map[int]positionTableRow) {
 
 // Computes a list transitorial "nodes" based on the lastpos, first post and follow post
 // of positionTable.
+//
+// Besides the start state, it returns the state to start from at the beginning of a line,
+// where rules anchored with "^" can match too.
+//
+// Sets of positions are found by its key in a map, and each position is only
+// checked against the alphabet symbols it matches, so big specs are built fast.
 func simplifyStates(
-	tokens []string,
+	tokens []alphabetSymbol,
 	initState []int,
-	positionTable map[int]positionTableRow) []*nodeSet {
+	positionTable []positionTableRow,
+	anchorRules map[int]int) ([]*nodeSet, *nodeSet) {
+
+	size := len(positionTable)
+	positionSymbols := indexPositionSymbols(tokens, positionTable)
+	states := make([]*nodeSet, 0)
+	queue := make([]*nodeSet, 0)
+	setsByKey := make(map[string]*nodeSet)
+
+	// Returns the set with the same positions, or adds it as a new one.
+	addSet := func(value bitset, headEnds []int) *nodeSet {
+		key := value.key()
+		if repeatedSet, setAlreadyExist := setsByKey[key]; setAlreadyExist {
+			return repeatedSet
+		}
+		newSet := &nodeSet{
+			id:          len(states),
+			value:       value,
+			transitions: make(map[string]*nodeSet),
+			headEnds:    headEnds,
+		}
+		for _, position := range value.items() {
+			newSet.isFinal = newSet.isFinal || positionTable[position].isFinal
+		}
+		setsByKey[key] = newSet
+		states = append(states, newSet)
+		queue = append(queue, newSet)
+		return newSet
+	}
 
-	inititialState := &nodeSet{id: 0, value: initState, transitions: make(map[string]*nodeSet)}
-	states := []*nodeSet{inititialState}
-	queue := []*nodeSet{inititialState}
+	addSet(closeAnchors(bitsetOf(size, initState), postfix.TRAILING_CONTEXT, positionTable, anchorRules))
+	lineStartValue, _ := closeAnchors(bitsetOf(size, initState), postfix.LINE_START, positionTable, anchorRules)
+	lineStartState := addSet(closeAnchors(lineStartValue, postfix.TRAILING_CONTEXT, positionTable, anchorRules))
 
 	for len(queue) > 0 {
 		currentState := queue[0] // Get a new element from queue
 		queue = queue[1:]        // Pop the element
 
-		// Get SET for each character
-		for _, token := range tokens {
-			// Being in the node A (currentState), computing the nextNode with transition "t"
-			//  ┌───┐    ┌───┐
-			//  │ A ┼─t─►│ B │
-			//  └───┘    └───┘
-			// The actions found for "t" will be returned as well, so node A can store them.
-			newSet, newActions := getNewNodeSetForToken(currentState.value, token, positionTable)
-
-			setAlreadyExist, repeatedSet := setExists(&newSet, states)
-
-			// If set does not exist append it
-			if !setAlreadyExist {
-				newSet.id = len(states)
-				currentState.transitions[token] = &newSet
-				currentState.actions = append(currentState.actions, newActions...)
-				queue = append(queue, &newSet)
-				states = append(states, &newSet)
-			} else {
-				currentState.transitions[token] = repeatedSet
-				currentState.actions = append(currentState.actions, newActions...)
+		// Being in the node A (currentState), computing the nextNode with transition "t"
+		//  ┌───┐    ┌───┐
+		//  │ A ┼─t─►│ B │
+		//  └───┘    └───┘
+		// B is the union of the followpos of the positions of A that match "t".
+		// The actions found for "t" are stored in node A.
+		nextSets := make([]bitset, len(tokens))
+		for _, position := range currentState.value.items() {
+			row := positionTable[position]
+			for _, token := range positionSymbols[position] {
+				if nextSets[token] == nil {
+					nextSets[token] = newBitset(size)
+				}
+				nextSets[token].union(row.followPos)
+				if row.action.Priority > -1 {
+					currentState.actions = append(currentState.actions, row.action)
+				}
+			}
+		}
+
+		// Most symbols are not matched by any position of a set, they would lead to
+		// the empty set, an absortion state. So those transitions are left out.
+		for i, token := range tokens {
+			if nextSets[i] != nil {
+				currentState.transitions[token.token] = addSet(closeAnchors(nextSets[i], postfix.TRAILING_CONTEXT, positionTable, anchorRules))
 			}
 		}
 	}
 
-	return states
+	return states, lineStartState
 }
 
-// Given a ID's nodeSet and a positionTable it computes the tokenSet
-// for the specific token.
-//
-// Also it returns the actions found for the token found. This actions will then be
-// transferred to the origin node.
-func getNewNodeSetForToken(items []int, token string, positionTable map[int]positionTableRow) (nodeSet, []Action) {
-	setItems := make([]int, 0, len(items))
-	actions := make([]Action, 0)
-
-	// Selecting rows from position table with desired ID's
-	for _, i := range items {
-		row := positionTable[i]
-		if row.token != token {
+// Returns for each position the index of the alphabet symbols it matches.
+// Since the interval symbols are sorted, they are found with a binary search.
+func indexPositionSymbols(tokens []alphabetSymbol, positionTable []positionTableRow) [][]int {
+	intervals := 0
+	actionTokens := make(map[string]int)
+	for i, token := range tokens {
+		if token.isAction {
+			actionTokens[token.token] = i
+		} else {
+			intervals++
+		}
+	}
+
+	positionSymbols := make([][]int, len(positionTable))
+	for position, row := range positionTable {
+		if row.token == "" || row.anchor != postfix.NO_ANCHOR {
+			continue
+		}
+		if row.ranges == nil {
+			if i, isAction := actionTokens[row.token]; isAction {
+				positionSymbols[position] = []int{i}
+			}
 			continue
 		}
-		setItems = append(setItems, row.followPos...)
-		if row.action.Priority > -1 {
-			actions = append(actions, row.action)
+		for _, r := range row.ranges {
+			i := sort.Search(intervals, func(i int) bool { return tokens[i].from >= r.From })
+			for ; i < intervals && tokens[i].to <= r.To; i++ {
+				positionSymbols[position] = append(positionSymbols[position], i)
+			}
 		}
 	}
+	return positionSymbols
+}
 
-	finalItems := removeDuplicates((setItems))
-	isFinal := false
-	for _, item := range finalItems {
-		if positionTable[item].isFinal {
-			isFinal = true
-			break
+// Anchors do not consume any symbol, so if a set contains an anchor of the given kind,
+// the positions that follow it are added to the set (the anchor itself is kept so
+// sets reached through it are still different). The set is modified in place.
+//
+// Also returns the rules of the anchors found, sorted by priority. For trailing
+// context anchors those are the rules whose pattern before "/" ends in this set.
+func closeAnchors(
+	items bitset,
+	anchor postfix.Anchor,
+	positionTable []positionTableRow,
+	anchorRules map[int]int) (bitset, []int) {
+
+	rules := make([]int, 0)
+	var closed map[int]bool
+	for changed := true; changed; {
+		changed = false
+		for position, rule := range anchorRules {
+			if positionTable[position].anchor != anchor || !items.has(position) || closed[position] {
+				continue
+			}
+			if closed == nil {
+				closed = make(map[int]bool)
+			}
+			closed[position] = true
+			changed = true
+			items.union(positionTable[position].followPos)
+			rules = append(rules, rule)
 		}
 	}
-
-	return nodeSet{
-		value:       removeDuplicates(setItems), // fcalculate the UNION of followPos
-		isFinal:     isFinal,
-		transitions: make(map[string]*nodeSet),
-		actions:     []Action{},
-	}, actions
+	if len(rules) < 2 {
+		return items, rules
+	}
+	sort.Ints(rules)
+	return items, removeDuplicates(rules)
 }
 
-// Function to check if a stateSet exists in a list based on value comparison
-// if it already exist returns true and the set itself.
-func setExists(newSet *nodeSet, sets []*nodeSet) (bool, *nodeSet) {
-	for _, existingSet := range sets {
-		if slicesAreEqual(newSet.value, existingSet.value) {
-			return true, existingSet
-		}
+// Checks if the token of a position table row matches an alphabet symbol.
+// Since the alphabet intervals are disjoint, matching its first rune is enough.
+func rowMatchesSymbol(row positionTableRow, symbol alphabetSymbol) bool {
+	if row.anchor != postfix.NO_ANCHOR {
+		return false
 	}
-	return false, nil
+	if symbol.isAction || row.ranges == nil {
+		return symbol.isAction && row.ranges == nil && row.token == symbol.token
+	}
+	i := sort.Search(len(row.ranges), func(i int) bool { return row.ranges[i].To >= symbol.from })
+	return i < len(row.ranges) && row.ranges[i].From <= symbol.from
 }
 
 // ====================================
 // BUILD DFA FROM INTERMEDIATE TRABLE
 // ====================================
 
-func convertToDFA(stateSets []*nodeSet, transitionTokens []string) *DFA {
+// Builds the DFA from the intermediate states. The transitions over action symbols
+// are left out: a state that has one recognizes the rule of that action, the one
+// with highest priority if many. So the states only reached by them are left out too.
+//
+// States are numbered in the order they are reached from the start state (breadth first),
+// moving with the alphabet symbols in order.
+func convertToDFA(stateSets []*nodeSet, transitionTokens []alphabetSymbol, lineStartState *nodeSet, rules []Action) *DFA {
 	// Create a mapping from stateSet ID to State
 	stateMap := make(map[int]*State)
-
-	// Convert stateSets to States
-	for _, s := range stateSets {
+	dfa := &DFA{Rules: rules}
+	// stateSet of each state, by its position in dfa.States
+	sets := make([]*nodeSet, 0, len(stateSets))
+
+	// Converts a stateSet to State the first time it is reached
+	addState := func(s *nodeSet) *State {
+		if state, exist := stateMap[s.id]; exist {
+			return state
+		}
 		SortActionsByPriority(s.actions)
-		stateMap[s.id] = &State{
-			Id:          fmt.Sprintf("%d", s.id), // Convert int ID to string
-			IsFinal:     s.isFinal,
+		state := &State{
+			Id:          fmt.Sprintf("%d", len(dfa.States)), // Convert int ID to string
+			Rule:        -1,
 			Transitions: make(map[Symbol]*State),
-			Actions:     s.actions,
+			HeadEnds:    s.headEnds,
+		}
+		if len(s.actions) > 0 {
+			state.Rule = s.actions[0].Priority
+			state.IsFinal = true
 		}
+		stateMap[s.id] = state
+		dfa.States = append(dfa.States, state)
+		sets = append(sets, s)
+		return state
 	}
 
-	// Populate transitions
-	for _, s := range stateSets {
-		currentState := stateMap[s.id]
+	dfa.StartState = addState(stateSets[0])
+	dfa.LineStartState = addState(lineStartState)
+
+	// Populate transitions, dfa.States grows while new states are reached
+	for i := 0; i < len(dfa.States); i++ {
+		currentState := dfa.States[i]
 		for _, token := range transitionTokens {
-			if nextStateSet, exists := s.transitions[token]; exists {
-				currentState.Transitions[token] = stateMap[nextStateSet.id]
+			nextStateSet, exists := sets[i].transitions[token.token]
+			if !exists || token.isAction {
+				continue
+			}
+			nextState := addState(nextStateSet)
+			if token.from == token.to {
+				currentState.Transitions[token.token] = nextState
+			} else {
+				currentState.Ranges = append(currentState.Ranges, RangeTransition{
+					From:  token.from,
+					To:    token.to,
+					State: nextState,
+				})
 			}
 		}
 	}
 
-	// Construct DFA
-	dfa := &DFA{
-		StartState: stateMap[0], // Assuming state ID 0 is the start state
-		States:     make([]*State, 0, len(stateMap)),
-	}
-
-	// Add all states to DFA
-	for _, state := range stateMap {
-		dfa.States = append(dfa.States, state)
-	}
-
 	return dfa
 }
 
@@ -394,7 +634,9 @@ func convertToDFA(stateSets []*nodeSet, transitionTokens []string) *DFA {
 // Remove the absortion states from a dfa in-place.
 //
 // - numFinalSymbol : refers the number of symbols that a node can have to transition.
-// Exregex: ab|(cc) = 3 different final symbols {a,b,c}
+// Exregex: ab|(cc) = 3 different final symbols {a,b,c}, [a-z]|b = 3 {a, b, c-z}
+//
+// Accepting states are never removed, even if all its transitions go back to itself.
 //
 // NOTE: this will make the resulting graph not DFA complient.
 func RemoveAbsortionStates(dfa *DFA, numFinalSymbol int) {
@@ -411,8 +653,13 @@ func RemoveAbsortionStates(dfa *DFA, numFinalSymbol int) {
 				count++
 			}
 		}
+		for _, r := range state.Ranges {
+			if state.Id == r.State.Id {
+				count++
+			}
+		}
 		// Interchange the count, for the number of final characters
-		if count == numFinalSymbol {
+		if count == numFinalSymbol && !state.IsFinal {
 			absStates = append(absStates, state)
 			absStatesIndex = append(absStatesIndex, i)
 			continue
@@ -431,6 +678,13 @@ func RemoveAbsortionStates(dfa *DFA, numFinalSymbol int) {
 		for _, keys := range keysToDelete {
 			delete(state.Transitions, keys)
 		}
+		ranges := state.Ranges[:0]
+		for _, r := range state.Ranges {
+			if !containsAbsortionState(r.State.Id, absStates) {
+				ranges = append(ranges, r)
+			}
+		}
+		state.Ranges = ranges
 	}
 
 	// Remove Absortion States itself
@@ -457,53 +711,6 @@ func containsAbsortionState(id string, list []*State) bool {
 //  UTILITY FUNCTIONS
 // ============================
 
-// Given 2 list of Nodes ID's it compute it computes its Union Set.
-func getNumberSet(a, b []int) []int {
-	unique := make(map[int]struct{})
-	result := []int{}
-
-	for _, str := range a {
-		if _, exists := unique[str]; !exists {
-			unique[str] = struct{}{}
-			result = append(result, str)
-		}
-	}
-
-	for _, str := range b {
-		if _, exists := unique[str]; !exists {
-			unique[str] = struct{}{}
-			result = append(result, str)
-		}
-	}
-
-	return result
-}
-
-// Helper function to check if two slices contain the same elements
-func slicesAreEqual(a, b []int) bool {
-	// fmt.Printf("\t %v %v \n", a, b)
-	if len(a) != len(b) {
-		return false
-	}
-
-	counts := make(map[int]int)
-
-	// Count occurrences in the first slice
-	for _, num := range a {
-		counts[num]++
-	}
-
-	// Check if second slice has the same elements
-	for _, num := range b {
-		if counts[num] == 0 {
-			return false
-		}
-		counts[num]--
-	}
-
-	return true
-}
-
 // Given a slice of int, remove its duplicates.
 func removeDuplicates(slice []int) []int {
 	seen := make(map[int]struct{})
@@ -519,6 +726,24 @@ func removeDuplicates(slice []int) []int {
 	return result
 }
 
+// Returns the symbols of transitions sorted, so they are always listed in the same
+// order: characters by its code point, then action symbols by its rule.
+func SortedSymbols(transitions map[Symbol]*State) []Symbol {
+	symbols := make([]Symbol, 0, len(transitions))
+	for symbol := range transitions {
+		symbols = append(symbols, symbol)
+	}
+	// Action symbols are numbers of more than one character, so shorter ones go first.
+	sort.Slice(symbols, func(i, j int) bool {
+		lenI, lenJ := utf8.RuneCountInString(symbols[i]), utf8.RuneCountInString(symbols[j])
+		if lenI != lenJ {
+			return lenI < lenJ
+		}
+		return symbols[i] < symbols[j]
+	})
+	return symbols
+}
+
 // Sort actions by priority
 func SortActionsByPriority(actions []Action) {
 	sort.Slice(actions, func(i, j int) bool {
@@ -0,0 +1,60 @@
+package dfa
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// Builds the regex of a spec like the ones of programming languages: many keywords
+// followed by identifiers, numbers, strings, operators and whitespace.
+func largeSpec(b *testing.B, keywords int) postfix.Regex {
+	random := rand.New(rand.NewSource(int64(keywords)))
+	patterns := make([]string, 0, keywords+6)
+	seen := make(map[string]bool)
+	for len(patterns) < keywords {
+		word := make([]byte, 2+random.Intn(8))
+		for i := range word {
+			word[i] = byte('a' + random.Intn(26))
+		}
+		if !seen[string(word)] {
+			seen[string(word)] = true
+			patterns = append(patterns, `"`+string(word)+`"`)
+		}
+	}
+	patterns = append(patterns,
+		"[a-zA-Z_][a-zA-Z0-9_]*",
+		"[0-9]+(\\.[0-9]+)?([eE][+\\-]?[0-9]+)?",
+		`"\""([^"\\]|\\.)*"\""`,
+		`[+\-*/=<>!]=?|&&|"||"`,
+		`[(){}\[\];,.]`,
+		`[ \t\n]+`)
+
+	rules := make([]postfix.Regex, len(patterns))
+	for i, pattern := range patterns {
+		rule, err := postfix.ParseRule(pattern, postfix.ParseOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		rules[i] = rule.Regex(postfix.Accept{Value: fmt.Sprint(i + 10), Action: postfix.Action{Priority: i}})
+	}
+	return postfix.Alt{Options: rules}
+}
+
+func BenchmarkNewDFA(b *testing.B) {
+	for _, keywords := range []int{25, 100, 200, 500} {
+		expresion := largeSpec(b, keywords)
+		for _, construction := range []Construction{DIRECT_CONSTRUCTION, THOMPSON_CONSTRUCTION} {
+			b.Run(fmt.Sprintf("%s/keywords=%d", construction, keywords), func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					if _, _, err := NewDFA(expresion, construction, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package dfa
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestAlphabetIsDisjoint(t *testing.T) {
+	expresion := []postfix.Symbol{
+		{Value: "[a-z]", Action: postfix.Action{Priority: -1}, Ranges: []postfix.RuneRange{{From: 'a', To: 'z'}}},
+		{Value: "b", Action: postfix.Action{Priority: -1}},
+		{Value: "[x-~]", Action: postfix.Action{Priority: -1}, Ranges: []postfix.RuneRange{{From: 'x', To: '~'}}},
+		{Value: "10", Action: postfix.Action{Priority: 0, Code: "{}"}},
+	}
+
+	alphabet := findFinalSymbols(expresion)
+
+	expected := []string{"a", "b", "c-w", "x-z", "{-~", "10"}
+	tokens := alphabetTokens(alphabet)
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, tokens)
+			break
+		}
+	}
+}
+
+// Builds the regex of a rule the same way the generator does.
+func ruleRegex(t *testing.T, priority int, pattern string) postfix.Regex {
+	rule, err := postfix.ParseRule(pattern, postfix.ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule.Regex(postfix.Accept{
+		Value:  fmt.Sprint(priority + 10),
+		Action: postfix.Action{Priority: priority, Code: "{}"}})
+}
+
+func TestAnchors(t *testing.T) {
+	expresion := postfix.Alt{Options: []postfix.Regex{ruleRegex(t, 0, "^a"), ruleRegex(t, 1, "b/c")}}
+
+	automata, numFinalSymbols, err := NewDFA(expresion, DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RemoveAbsortionStates(automata, numFinalSymbols)
+
+	if _, ok := automata.StartState.Transitions["a"]; ok {
+		t.Errorf("rule \"^a\" should not match outside the start of a line")
+	}
+	if _, ok := automata.LineStartState.Transitions["a"]; !ok {
+		t.Errorf("rule \"^a\" should match at the start of a line")
+	}
+
+	afterB := automata.StartState.Transitions["b"]
+	if afterB == nil || len(afterB.HeadEnds) != 1 || afterB.HeadEnds[0] != 1 {
+		t.Fatalf("rule \"b/c\" pattern should end after reading \"b\"")
+	}
+	afterC := afterB.Transitions["c"]
+	if afterC == nil || afterC.Rule != 1 || !automata.Rules[afterC.Rule].TrailingContext {
+		t.Errorf("rule \"b/c\" should be accepted after reading \"bc\"")
+	}
+}
+
+func TestFinalStatesHaveOneRule(t *testing.T) {
+	expresion := postfix.Alt{Options: []postfix.Regex{ruleRegex(t, 0, "if"), ruleRegex(t, 1, "[a-z]+")}}
+
+	automata, _, err := NewDFA(expresion, DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	afterI := step(automata.StartState, 'i')
+	if afterI == nil || !afterI.IsFinal || afterI.Rule != 1 {
+		t.Fatalf("\"i\" should only be recognized by rule 1")
+	}
+	afterIf := step(afterI, 'f')
+	if afterIf == nil || !afterIf.IsFinal || afterIf.Rule != 0 {
+		t.Errorf("\"if\" should be recognized by rule 0, the one with highest priority")
+	}
+	if automata.StartState.IsFinal || automata.StartState.Rule != -1 {
+		t.Errorf("the start state should not recognize any rule")
+	}
+}
+
+func TestBuildASTErrors(t *testing.T) {
+	literal := postfix.Symbol{Value: "a", Action: postfix.Action{Priority: -1}}
+	action := postfix.Symbol{Value: "11", Action: postfix.Action{Priority: 1}}
+	cases := []struct {
+		expresion []postfix.Symbol
+		expected  string
+	}{
+		{[]postfix.Symbol{literal, postfix.OPERATORS["|"], action}, `operador "|" de la regla 2`},
+		{[]postfix.Symbol{postfix.OPERATORS["*"], literal}, `operador "*" en la posición 0`},
+		{[]postfix.Symbol{literal, literal}, "sobran 1 operandos"},
+		{[]postfix.Symbol{}, "vacía"},
+	}
+
+	for _, c := range cases {
+		_, err := BuildAST(c.expresion)
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("expected an error containing %q, got %v", c.expected, err)
+		}
+	}
+}
+
+// Any pattern must either be rejected with an error or produce a DFA, never panic.
+func FuzzNewDFA(f *testing.F) {
+	for _, seed := range []string{"a|", "*b", "(a|b)*c", "[a-z]+", `"a"?`, "^a/b$", "(?i:ab)", "a{2,3}", "[^\\]", `\p{Greek}`, "((a)", "()", ""} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, pattern string) {
+		// Repetitions of repetitions grow too much to build, Ex: (a{1000}){1000}
+		if len(pattern) > 64 || strings.Count(pattern, "{") > 1 {
+			t.Skip()
+		}
+
+		raw := make([]postfix.RawSymbol, 0, len(pattern))
+		for _, r := range pattern {
+			raw = append(raw, postfix.RawSymbol{Value: string(r), Action: postfix.Action{Priority: -1}})
+		}
+		_, postfixExpr, err := postfix.RegexToPostfix(raw)
+		if err != nil {
+			return
+		}
+		if _, err := BuildAST(postfixExpr); err != nil {
+			t.Errorf("%q: valid pattern has an invalid postfix: %v", pattern, err)
+		}
+
+		// A DFA can have 2^n states for n positions. Ex: [ab]*a[ab]{20}
+		positions := 0
+		for _, symbol := range postfixExpr {
+			if !symbol.IsOperator {
+				positions++
+			}
+		}
+		if positions > 12 {
+			return
+		}
+
+		rule, err := postfix.ParseRule(pattern, postfix.ParseOptions{})
+		if err != nil {
+			return
+		}
+		expresion := rule.Regex(postfix.Accept{Value: "10", Action: postfix.Action{Priority: 0}})
+		if _, _, err := NewDFA(expresion, DIRECT_CONSTRUCTION, nil); err != nil {
+			t.Errorf("%q: valid pattern failed to build a DFA: %v", pattern, err)
+		}
+	})
+}
+
+func TestPositionTable(t *testing.T) {
+	expresion := postfix.Alt{Options: []postfix.Regex{ruleRegex(t, 0, "ab"), ruleRegex(t, 1, "c*")}}
+	rows, err := PositionTable(expresion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Postfix: ab·10·c*11·|
+	expected := []string{
+		"0 a rule 0 followpos [1]",
+		"1 b rule 0 followpos [3]",
+		"3 10 rule 0 followpos [10]",
+		"5 c rule 1 followpos [5 7]",
+		"7 11 rule 1 followpos [10]",
+		"10 # rule -1 followpos []",
+	}
+	for i, row := range rows {
+		if got := fmt.Sprintf("%d %s rule %d followpos %v", row.Position, row.Symbol, row.Rule, row.FollowPos); i >= len(expected) || got != expected[i] {
+			t.Errorf("row %d: expected %q got %q", i, expected[min(i, len(expected)-1)], got)
+		}
+	}
+	if len(rows) != len(expected) {
+		t.Errorf("expected %d rows, got %d", len(expected), len(rows))
+	}
+}
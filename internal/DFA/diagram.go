@@ -0,0 +1,411 @@
+package dfa
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// This file writes the diagrams of the syntax tree and the DFA. DOT and PNG diagrams
+// are drawn by Graphviz ("dot" for PNG), Mermaid and SVG diagrams don't need it.
+
+// Format of a diagram, given by the extension of the file it is written to.
+type DiagramFormat string
+
+const (
+	DOT_DIAGRAM     DiagramFormat = "dot"     // Graphviz source. Ex: tree.dot, tree.gv
+	MERMAID_DIAGRAM DiagramFormat = "mermaid" // Mermaid flowchart. Ex: tree.mmd, tree.mermaid
+	SVG_DIAGRAM     DiagramFormat = "svg"     // Self-contained image, laid out without Graphviz
+	PNG_DIAGRAM     DiagramFormat = "png"     // Image rendered by Graphviz, which must be installed
+)
+
+// Returns the format of a diagram by the extension of its path.
+func DiagramFormatOf(path string) (DiagramFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		return DOT_DIAGRAM, nil
+	case ".mmd", ".mermaid":
+		return MERMAID_DIAGRAM, nil
+	case ".svg":
+		return SVG_DIAGRAM, nil
+	case ".png":
+		return PNG_DIAGRAM, nil
+	}
+	return "", fmt.Errorf("unknown diagram format for %q, use .dot, .mmd, .svg or .png", path)
+}
+
+// Returns the format of the given name. Ex: "dot", "mermaid", "svg"
+func ParseDiagramFormat(name string) (DiagramFormat, error) {
+	switch format := DiagramFormat(strings.ToLower(name)); format {
+	case DOT_DIAGRAM, MERMAID_DIAGRAM, SVG_DIAGRAM, PNG_DIAGRAM:
+		return format, nil
+	case "gv":
+		return DOT_DIAGRAM, nil
+	case "mmd":
+		return MERMAID_DIAGRAM, nil
+	}
+	return "", fmt.Errorf("unknown diagram format %q, use %q, %q, %q or %q", name, DOT_DIAGRAM, MERMAID_DIAGRAM, SVG_DIAGRAM, PNG_DIAGRAM)
+}
+
+// Writes the diagram of the syntax tree of expresion to path, in the format of its extension.
+func WriteASTDiagram(expresion postfix.Regex, path string) error {
+	format, err := DiagramFormatOf(path)
+	if err != nil {
+		return err
+	}
+	if format == PNG_DIAGRAM {
+		postfixExpr, err := postfix.ToPostfix(expresion)
+		if err != nil {
+			return err
+		}
+		ast, err := BuildAST(postfixExpr)
+		if err != nil {
+			return err
+		}
+		return RenderAST(ast, path)
+	}
+	diagram, err := ASTDiagram(expresion, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(diagram), 0o644)
+}
+
+// Returns the diagram of the syntax tree of expresion in a text format: DOT, Mermaid or SVG.
+func ASTDiagram(expresion postfix.Regex, format DiagramFormat) (string, error) {
+	postfixExpr, err := postfix.ToPostfix(expresion)
+	if err != nil {
+		return "", err
+	}
+	ast, err := BuildAST(postfixExpr)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case DOT_DIAGRAM:
+		return GenerateDOT_AST(ast), nil
+	case MERMAID_DIAGRAM:
+		return GenerateMermaid_AST(ast), nil
+	case SVG_DIAGRAM:
+		return GenerateSVG_AST(ast), nil
+	}
+	return "", fmt.Errorf("the syntax tree can't be drawn as %s text", format)
+}
+
+// Writes the diagram of the DFA to path, in the format of its extension.
+func WriteDFADiagram(dfa *DFA, path string) error {
+	format, err := DiagramFormatOf(path)
+	if err != nil {
+		return err
+	}
+	if format == PNG_DIAGRAM {
+		return RenderDFA(dfa, path)
+	}
+	diagram, err := DFADiagram(dfa, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(diagram), 0o644)
+}
+
+// Returns the diagram of the DFA in a text format: DOT, Mermaid or SVG.
+func DFADiagram(dfa *DFA, format DiagramFormat) (string, error) {
+	switch format {
+	case DOT_DIAGRAM:
+		return GenerateDOT_DFA(dfa), nil
+	case MERMAID_DIAGRAM:
+		return GenerateMermaid_DFA(dfa), nil
+	case SVG_DIAGRAM:
+		return GenerateSVG_DFA(dfa), nil
+	}
+	return "", fmt.Errorf("the DFA can't be drawn as %s text", format)
+}
+
+// ============================
+//  MERMAID
+// ============================
+
+// Generates a Mermaid flowchart of the syntax tree, from the root to the leaves.
+func GenerateMermaid_AST(root node) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+
+	nodeCount := 0
+	var addNode func(n node) string
+	addNode = func(n node) string {
+		nodeID := fmt.Sprintf("n%d", nodeCount)
+		nodeCount++
+		sb.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", nodeID, mermaidText(n.Value)))
+		if n.IsOperator {
+			for _, operand := range n.Children {
+				sb.WriteString(fmt.Sprintf("    %s --> %s\n", nodeID, addNode(operand)))
+			}
+		}
+		return nodeID
+	}
+	addNode(root)
+	return sb.String()
+}
+
+// Generates a Mermaid flowchart of the DFA, from left to right. Final states are
+// drawn with a double circle, and the transitions between two states share an arrow.
+func GenerateMermaid_DFA(dfa *DFA) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	sb.WriteString("    start[\" \"] --> s" + dfa.StartState.Id + "\n")
+	sb.WriteString("    style start fill:none,stroke:none\n")
+
+	for _, state := range dfa.States {
+		if state.IsFinal {
+			sb.WriteString(fmt.Sprintf("    s%s(((\"%s\")))\n", state.Id, state.Id))
+		} else {
+			sb.WriteString(fmt.Sprintf("    s%s((\"%s\"))\n", state.Id, state.Id))
+		}
+	}
+	for _, edge := range diagramEdges(dfa) {
+		sb.WriteString(fmt.Sprintf("    s%s -->|\"%s\"| s%s\n", edge.from.Id, mermaidText(edge.label), edge.to.Id))
+	}
+	return sb.String()
+}
+
+// Escapes the characters Mermaid gives a meaning within quoted text.
+func mermaidText(text string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "&", "#amp;", "\n", `\n`).Replace(text)
+}
+
+// Transitions of the DFA between two states, joined in a single arrow.
+type diagramEdge struct {
+	from  *State
+	to    *State
+	label string // Symbols of all the transitions. Ex: "a, [c-z]"
+}
+
+// Returns the transitions of the DFA joined by the states they connect, in the
+// order of the states and its runes. Intervals of runes next to each other are joined.
+func diagramEdges(dfa *DFA) []diagramEdge {
+	edges := make([]diagramEdge, 0)
+	for _, state := range dfa.States {
+		byTarget := make(map[*State]int)
+		intervals := make([][]RangeTransition, 0)
+		for _, t := range runeTransitions(state) {
+			i, exist := byTarget[t.State]
+			if !exist {
+				i = len(intervals)
+				byTarget[t.State] = i
+				intervals = append(intervals, nil)
+			}
+			if last := len(intervals[i]) - 1; last >= 0 && intervals[i][last].To+1 == t.From {
+				intervals[i][last].To = t.To
+				continue
+			}
+			intervals[i] = append(intervals[i], t)
+		}
+		for _, targetIntervals := range intervals {
+			symbols := make([]string, len(targetIntervals))
+			for i, t := range targetIntervals {
+				symbols[i] = formatRange(t)
+				if t.From == t.To {
+					symbols[i] = strings.Trim(strconv.Quote(string(t.From)), `"`)
+				}
+			}
+			edges = append(edges, diagramEdge{from: state, to: targetIntervals[0].State, label: strings.Join(symbols, ", ")})
+		}
+	}
+	return edges
+}
+
+// ============================
+//  SVG
+// ============================
+
+const (
+	SVG_MARGIN      = 30.0
+	SVG_NODE_RADIUS = 18.0
+	SVG_LEVEL_GAP   = 70.0  // Space between the levels of the tree
+	SVG_LEAF_GAP    = 45.0  // Space between the leaves of the tree
+	SVG_LAYER_GAP   = 130.0 // Space between the states of the DFA at different distance from the start
+	SVG_STATE_GAP   = 80.0  // Space between the states of the DFA at the same distance
+)
+
+// Style shared by all SVG diagrams, so they don't need anything else to be displayed.
+const svgStyle = `<style>
+  circle { fill: #fff; stroke: #333; stroke-width: 1.5; }
+  line, path { fill: none; stroke: #555; stroke-width: 1.2; }
+  text { font-family: monospace; font-size: 12px; text-anchor: middle; dominant-baseline: central; }
+  .label { font-size: 11px; fill: #1f4e99; }
+</style>
+<defs>
+  <marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="7" markerHeight="7" orient="auto-start-reverse">
+    <path d="M 0 0 L 10 5 L 0 10 z" style="fill: #555; stroke: none;"/>
+  </marker>
+</defs>
+`
+
+type point struct {
+	x float64
+	y float64
+}
+
+// Generates a SVG image of the syntax tree. Leaves are placed from left to right
+// and each operator is centered over its operands.
+func GenerateSVG_AST(root node) string {
+	var nodes strings.Builder
+	var lines strings.Builder
+	nextLeaf := 0
+	maxDepth := 0
+
+	var place func(n node, depth int) point
+	place = func(n node, depth int) point {
+		maxDepth = max(maxDepth, depth)
+		var position point
+		if !n.IsOperator || len(n.Children) == 0 {
+			position = point{x: SVG_MARGIN + float64(nextLeaf)*SVG_LEAF_GAP, y: SVG_MARGIN + float64(depth)*SVG_LEVEL_GAP}
+			nextLeaf++
+		} else {
+			children := make([]point, len(n.Children))
+			for i, child := range n.Children {
+				children[i] = place(child, depth+1)
+			}
+			position = point{x: (children[0].x + children[len(children)-1].x) / 2, y: SVG_MARGIN + float64(depth)*SVG_LEVEL_GAP}
+			for _, child := range children {
+				from, to := shortenLine(position, child, SVG_NODE_RADIUS)
+				fmt.Fprintf(&lines, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", from.x, from.y, to.x, to.y)
+			}
+		}
+		fmt.Fprintf(&nodes, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\"/>\n", position.x, position.y, SVG_NODE_RADIUS)
+		fmt.Fprintf(&nodes, "<text x=\"%.1f\" y=\"%.1f\">%s</text>\n", position.x, position.y, html.EscapeString(n.Value))
+		return position
+	}
+	place(root, 0)
+
+	width := 2*SVG_MARGIN + float64(max(nextLeaf-1, 0))*SVG_LEAF_GAP
+	height := 2*SVG_MARGIN + float64(maxDepth)*SVG_LEVEL_GAP
+	return svgDocument(width, height, lines.String()+nodes.String())
+}
+
+// Generates a SVG image of the DFA. States are placed in columns by its distance
+// from the start state. Transitions to the next columns are straight arrows, the others are curved.
+func GenerateSVG_DFA(dfa *DFA) string {
+	edges := diagramEdges(dfa)
+	next := make(map[*State][]*State)
+	for _, edge := range edges {
+		next[edge.from] = append(next[edge.from], edge.to)
+	}
+
+	// Distance of each state from the start state (breadth first)
+	layers := map[*State]int{dfa.StartState: 0}
+	queue := []*State{dfa.StartState}
+	if dfa.LineStartState != nil && dfa.LineStartState != dfa.StartState {
+		layers[dfa.LineStartState] = 0
+		queue = append(queue, dfa.LineStartState)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, to := range next[state] {
+			if _, visited := layers[to]; !visited {
+				layers[to] = layers[state] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	// States not reached from the start go in the last column
+	numColumns := 0
+	for _, state := range dfa.States {
+		numColumns = max(numColumns, layers[state]+1)
+	}
+	for _, state := range dfa.States {
+		if _, reached := layers[state]; !reached {
+			layers[state] = numColumns
+		}
+	}
+	numColumns = 0
+	for _, state := range dfa.States {
+		numColumns = max(numColumns, layers[state]+1)
+	}
+
+	positions := make(map[*State]point)
+	statesInLayer := make([]int, numColumns)
+	maxStates := 0
+	for _, state := range dfa.States {
+		layer := layers[state]
+		positions[state] = point{
+			x: 2*SVG_MARGIN + float64(layer)*SVG_LAYER_GAP,
+			y: SVG_MARGIN + SVG_NODE_RADIUS + float64(statesInLayer[layer])*SVG_STATE_GAP,
+		}
+		statesInLayer[layer]++
+		maxStates = max(maxStates, statesInLayer[layer])
+	}
+
+	var body strings.Builder
+	// Arrow to the start state
+	start := positions[dfa.StartState]
+	fmt.Fprintf(&body, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" marker-end=\"url(#arrow)\"/>\n",
+		start.x-2*SVG_MARGIN+5, start.y, start.x-SVG_NODE_RADIUS, start.y)
+
+	for _, edge := range edges {
+		from, to := positions[edge.from], positions[edge.to]
+		label := html.EscapeString(edge.label)
+		switch {
+		case edge.from == edge.to:
+			// Loop over the state
+			fmt.Fprintf(&body, "<path d=\"M %.1f %.1f C %.1f %.1f %.1f %.1f %.1f %.1f\" marker-end=\"url(#arrow)\"/>\n",
+				from.x-8, from.y-SVG_NODE_RADIUS+2, from.x-25, from.y-60, from.x+25, from.y-60, from.x+8, from.y-SVG_NODE_RADIUS+2)
+			fmt.Fprintf(&body, "<text class=\"label\" x=\"%.1f\" y=\"%.1f\">%s</text>\n", from.x, from.y-55, label)
+		case to.x > from.x && layers[edge.to] == layers[edge.from]+1:
+			a, b := shortenLine(from, to, SVG_NODE_RADIUS)
+			fmt.Fprintf(&body, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" marker-end=\"url(#arrow)\"/>\n", a.x, a.y, b.x, b.y)
+			fmt.Fprintf(&body, "<text class=\"label\" x=\"%.1f\" y=\"%.1f\">%s</text>\n", (a.x+b.x)/2, (a.y+b.y)/2-8, label)
+		default:
+			// Curve to the side, so it does not cross the states in between
+			middle := point{x: (from.x + to.x) / 2, y: (from.y + to.y) / 2}
+			dx, dy := to.x-from.x, to.y-from.y
+			length := math.Max(math.Hypot(dx, dy), 1)
+			control := point{x: middle.x + dy/length*50, y: middle.y - dx/length*50}
+			a, _ := shortenLine(from, control, SVG_NODE_RADIUS)
+			b, _ := shortenLine(to, control, SVG_NODE_RADIUS)
+			fmt.Fprintf(&body, "<path d=\"M %.1f %.1f Q %.1f %.1f %.1f %.1f\" marker-end=\"url(#arrow)\"/>\n", a.x, a.y, control.x, control.y, b.x, b.y)
+			fmt.Fprintf(&body, "<text class=\"label\" x=\"%.1f\" y=\"%.1f\">%s</text>\n", (middle.x+control.x)/2, (middle.y+control.y)/2, label)
+		}
+	}
+
+	// States are grouped with its id, so they can be found within the image
+	for _, state := range dfa.States {
+		p := positions[state]
+		fmt.Fprintf(&body, "<g class=\"state\" data-state=\"%s\">\n", state.Id)
+		fmt.Fprintf(&body, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\"/>\n", p.x, p.y, SVG_NODE_RADIUS)
+		if state.IsFinal {
+			fmt.Fprintf(&body, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\"/>\n", p.x, p.y, SVG_NODE_RADIUS-4)
+		}
+		fmt.Fprintf(&body, "<text x=\"%.1f\" y=\"%.1f\">%s</text>\n</g>\n", p.x, p.y, state.Id)
+	}
+
+	width := 3*SVG_MARGIN + float64(max(numColumns-1, 0))*SVG_LAYER_GAP + 2*SVG_NODE_RADIUS
+	height := 2*SVG_MARGIN + float64(max(maxStates-1, 0))*SVG_STATE_GAP + 2*SVG_NODE_RADIUS
+	return svgDocument(width, height, body.String())
+}
+
+// Returns the ends of the line between the centers of two circles, on their border.
+func shortenLine(from, to point, radius float64) (point, point) {
+	dx, dy := to.x-from.x, to.y-from.y
+	length := math.Hypot(dx, dy)
+	if length <= 2*radius {
+		return from, to
+	}
+	ux, uy := dx/length*radius, dy/length*radius
+	return point{x: from.x + ux, y: from.y + uy}, point{x: to.x - ux, y: to.y - uy}
+}
+
+// Returns the SVG image with the given content. There is space above it for the
+// loops of the states at the top.
+func svgDocument(width, height float64, body string) string {
+	return fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 -40 %.0f %.0f\">\n%s%s</svg>\n",
+		width, height+40, width, height+40, svgStyle, body)
+}
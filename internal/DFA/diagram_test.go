@@ -0,0 +1,79 @@
+package dfa
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestWriteDiagrams(t *testing.T) {
+	expresion := postfix.Alt{Options: []postfix.Regex{
+		ruleRegex(t, 0, "if"),
+		ruleRegex(t, 1, "[a-z]+"),
+		ruleRegex(t, 2, `"<"|"&"`),
+	}}
+	automata, numFinalSymbols, err := NewDFA(expresion, DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RemoveAbsortionStates(automata, numFinalSymbols)
+
+	dir := t.TempDir()
+	for _, name := range []string{"tree.dot", "tree.mmd", "tree.svg", "dfa.gv", "dfa.mermaid", "dfa.svg"} {
+		path := filepath.Join(dir, name)
+		if strings.HasPrefix(name, "tree") {
+			err = WriteASTDiagram(expresion, path)
+		} else {
+			err = WriteDFADiagram(automata, path)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch filepath.Ext(name) {
+		case ".svg":
+			// Labels are escaped, so the image is valid XML
+			decoder := xml.NewDecoder(strings.NewReader(string(content)))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("%s is not valid XML: %v", name, err)
+				}
+			}
+		case ".mermaid":
+			// Transitions between the same states share an arrow
+			if !strings.Contains(string(content), `s0 -->|"[a-h], [j-z]"| s2`) {
+				t.Errorf("%s has no arrow for [a-h] and [j-z]:\n%s", name, content)
+			}
+		}
+	}
+
+	if err := WriteDFADiagram(automata, filepath.Join(dir, "dfa.txt")); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// Labels of DOT diagrams are quoted, so any rune is valid, and DFAs without states are empty.
+func TestDOTDiagrams(t *testing.T) {
+	root := node{Value: "·", IsOperator: true, Children: []node{{Value: `\`}, {Value: `"`}}}
+	dot := GenerateDOT_AST(root)
+	for _, label := range []string{`[label="\\"]`, `[label="\""]`} {
+		if !strings.Contains(dot, label) {
+			t.Errorf("expected %s in\n%s", label, dot)
+		}
+	}
+
+	if dot := GenerateDOT_DFA(&DFA{}); dot != "digraph DFA {\n    rankdir=LR;\n}\n" {
+		t.Errorf("expected an empty digraph, got\n%s", dot)
+	}
+}
@@ -0,0 +1,202 @@
+package dfa
+
+import (
+	"sort"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// This file contains the alternative construction of the DFA: a Thompson NFA is
+// built from the postfix expresion and then converted with the subset construction.
+// It produces a DFA equivalent to the direct method, useful to compare both.
+
+// Builds a Thompson NFA from a postfix expresion. Every operand is a fragment of two
+// states joined by its symbol, and operators join fragments with epsilon transitions:
+//
+//	a    : (s)-a->(e)
+//	ab·  : (a.s)-a->(a.e)-ε->(b.s)-b->(b.e)
+//	ab|  : (s)-ε->(a.s) (s)-ε->(b.s) (a.e)-ε->(e) (b.e)-ε->(e)
+//	a*   : (s)-ε->(a.s) (s)-ε->(e) (a.e)-ε->(a.s) (a.e)-ε->(e)
+//	a+   : same as a* without (s)-ε->(e)
+//
+// The expresion must be valid (see BuildAST). Returns the start state and the
+// number of states, whose ids go from 0 to that number.
+func buildNFA(postfixSymbols []postfix.Symbol) (*nfaState, int) {
+	count := 0
+	newState := func() *nfaState {
+		count++
+		return &nfaState{id: count - 1, position: -1}
+	}
+
+	stack := make([]nfaFragment, 0)
+	pop := func() nfaFragment {
+		fragment := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return fragment
+	}
+
+	for i, symbol := range postfixSymbols {
+		if !symbol.IsOperator {
+			start, end := newState(), newState()
+			if symbol.Value == "ε" {
+				start.epsilon = append(start.epsilon, end)
+			} else {
+				start.position = i
+				start.symbol = positionTableRow{
+					token:  symbol.Value,
+					ranges: symbolRanges(symbol),
+					anchor: symbol.Anchor,
+					action: Action{
+						Priority:        symbol.Action.Priority,
+						Code:            symbol.Action.Code,
+						TrailingContext: symbol.Action.TrailingContext,
+						TrailingLength:  symbol.Action.TrailingLength,
+					},
+				}
+				start.next = end
+			}
+			stack = append(stack, nfaFragment{start: start, end: end})
+			continue
+		}
+
+		switch symbol.Value {
+		case "·":
+			right, left := pop(), pop()
+			left.end.epsilon = append(left.end.epsilon, right.start)
+			stack = append(stack, nfaFragment{start: left.start, end: right.end})
+		case "|":
+			right, left := pop(), pop()
+			start, end := newState(), newState()
+			start.epsilon = append(start.epsilon, left.start, right.start)
+			left.end.epsilon = append(left.end.epsilon, end)
+			right.end.epsilon = append(right.end.epsilon, end)
+			stack = append(stack, nfaFragment{start: start, end: end})
+		case "*", "+":
+			inner := pop()
+			start, end := newState(), newState()
+			start.epsilon = append(start.epsilon, inner.start)
+			if symbol.Value == "*" {
+				start.epsilon = append(start.epsilon, end)
+			}
+			inner.end.epsilon = append(inner.end.epsilon, inner.start, end)
+			stack = append(stack, nfaFragment{start: start, end: end})
+		}
+	}
+
+	fragment := pop()
+	fragment.end.isFinal = true
+	return fragment.start, count
+}
+
+// Computes the states reachable from the given ones without consuming any rune.
+//
+// Trailing context anchors are always crossed, the rules they belong to are returned
+// sorted by priority, since their pattern before "/" ends here. Line start anchors
+// are only crossed for the state at the beginning of a line.
+func epsilonClosure(
+	states []*nfaState,
+	lineStart bool,
+	anchorRules map[int]int) ([]*nfaState, []int) {
+
+	visited := make(map[int]bool, len(states))
+	result := make([]*nfaState, 0, len(states))
+	rules := make([]int, 0)
+	stack := append([]*nfaState{}, states...)
+
+	for len(stack) > 0 {
+		state := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[state.id] {
+			continue
+		}
+		visited[state.id] = true
+		result = append(result, state)
+
+		stack = append(stack, state.epsilon...)
+		switch state.symbol.anchor {
+		case postfix.TRAILING_CONTEXT:
+			rules = append(rules, anchorRules[state.position])
+			stack = append(stack, state.next)
+		case postfix.LINE_START:
+			if lineStart {
+				stack = append(stack, state.next)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
+	sort.Ints(rules)
+	return result, removeDuplicates(rules)
+}
+
+// Computes the states reached from states with a transition over token, and the
+// actions of the rules accepted by it.
+func moveNFA(states []*nfaState, token alphabetSymbol) ([]*nfaState, []Action) {
+	result := make([]*nfaState, 0)
+	actions := make([]Action, 0)
+	for _, state := range states {
+		if state.next == nil || !rowMatchesSymbol(state.symbol, token) {
+			continue
+		}
+		result = append(result, state.next)
+		if state.symbol.action.Priority > -1 {
+			actions = append(actions, state.symbol.action)
+		}
+	}
+	return result, actions
+}
+
+// Converts a NFA to the same intermediate states the direct method produces, where
+// each one stands for the set of NFA states (by id) it can be in.
+//
+// Besides the start state, it returns the state to start from at the beginning of a line,
+// where rules anchored with "^" can match too.
+func subsetConstruction(
+	tokens []alphabetSymbol,
+	nfaStart *nfaState,
+	numNFAStates int,
+	anchorRules map[int]int) ([]*nodeSet, *nodeSet) {
+
+	nfaSets := make([][]*nfaState, 0)
+	states := make([]*nodeSet, 0)
+	queue := make([]*nodeSet, 0)
+	setsByKey := make(map[string]*nodeSet)
+
+	// Returns the set with the same NFA states, or adds it as a new one.
+	addSet := func(closure []*nfaState, headEnds []int) *nodeSet {
+		newSet := &nodeSet{transitions: make(map[string]*nodeSet), headEnds: headEnds, value: newBitset(numNFAStates)}
+		for _, state := range closure {
+			newSet.value.add(state.id)
+			newSet.isFinal = newSet.isFinal || state.isFinal
+		}
+		key := newSet.value.key()
+		if repeatedSet, setAlreadyExist := setsByKey[key]; setAlreadyExist {
+			return repeatedSet
+		}
+		newSet.id = len(states)
+		setsByKey[key] = newSet
+		states = append(states, newSet)
+		nfaSets = append(nfaSets, closure)
+		queue = append(queue, newSet)
+		return newSet
+	}
+
+	addSet(epsilonClosure([]*nfaState{nfaStart}, false, anchorRules))
+	lineStartState := addSet(epsilonClosure([]*nfaState{nfaStart}, true, anchorRules))
+
+	for len(queue) > 0 {
+		currentState := queue[0]
+		queue = queue[1:]
+
+		for _, token := range tokens {
+			reached, newActions := moveNFA(nfaSets[currentState.id], token)
+			if len(reached) == 0 {
+				continue
+			}
+			currentState.transitions[token.token] = addSet(epsilonClosure(reached, false, anchorRules))
+			currentState.actions = append(currentState.actions, newActions...)
+		}
+	}
+
+	return states, lineStartState
+}
@@ -0,0 +1,82 @@
+package dfa
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// Moves from state with r, nil if there is no transition.
+func step(state *State, r rune) *State {
+	if next, exist := state.Transitions[string(r)]; exist {
+		return next
+	}
+	for _, t := range state.Ranges {
+		if t.From <= r && r <= t.To {
+			return t.State
+		}
+	}
+	return nil
+}
+
+// Describes the DFA after reading each prefix of input: the winning rule and the
+// rules whose pattern before "/" ends there.
+func trace(start *State, input []rune) []string {
+	result := make([]string, 0, len(input)+1)
+	state := start
+	for i := 0; state != nil; i++ {
+		result = append(result, fmt.Sprintf("%d %v", state.Rule, state.HeadEnds))
+		if i == len(input) {
+			break
+		}
+		state = step(state, input[i])
+	}
+	return result
+}
+
+func TestConstructionsAreEquivalent(t *testing.T) {
+	ruleSets := [][]string{
+		{"if", "[a-z]+", "[0-9]+", " +"},
+		{"a*b", "(a|b)*abb", "b+", "a?a?a?"},
+		{"^a+", "a/b", "ab$", "[ab]"},
+		{"(?i:select)", "[a-zA-Z]+", `\s`},
+		{"a{2,3}", "(ab){1,}", "a|b|\n"},
+		{"((a|\"\")+b)*", "[^a]", "(a+)+"},
+	}
+	alphabet := []rune("abAB01 \nSELECTselect")
+	random := rand.New(rand.NewSource(33))
+
+	for _, patterns := range ruleSets {
+		rules := make([]postfix.Regex, len(patterns))
+		for i, pattern := range patterns {
+			rules[i] = ruleRegex(t, i, pattern)
+		}
+		expresion := postfix.Alt{Options: rules}
+
+		direct, _, err := NewDFA(expresion, DIRECT_CONSTRUCTION, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		thompson, _, err := NewDFA(expresion, THOMPSON_CONSTRUCTION, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for range 300 {
+			input := make([]rune, random.Intn(12))
+			for i := range input {
+				input[i] = alphabet[random.Intn(len(alphabet))]
+			}
+
+			if expected, got := trace(direct.StartState, input), trace(thompson.StartState, input); !slices.Equal(expected, got) {
+				t.Errorf("%v on %q: direct %v, thompson %v", patterns, string(input), expected, got)
+			}
+			if expected, got := trace(direct.LineStartState, input), trace(thompson.LineStartState, input); !slices.Equal(expected, got) {
+				t.Errorf("%v on %q at line start: direct %v, thompson %v", patterns, string(input), expected, got)
+			}
+		}
+	}
+}
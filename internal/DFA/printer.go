@@ -7,85 +7,18 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
-)
+	"unicode"
 
-func intSliceToString(slice []int) string {
-	strs := make([]string, len(slice))
-	for i, v := range slice {
-		strs[i] = strconv.Itoa(v)
-	}
-	return strings.Join(strs, ", ")
-}
-
-func printPositionTable(table map[int]positionTableRow) {
-	fmt.Printf("%-5s %-10s %-8s %-8s %-15s %-15s %-15s %s\n",
-		"Key", "Token", "Nullable", "IsFinal", "FirstPos", "LastPos", "FollowPos", "Actions")
-	fmt.Println(strings.Repeat("-", 80))
-
-	for key, row := range table {
-		actions := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(row.action)), ","), "[]")
-		fmt.Printf("%-5d %-10s %-8t %-8t %-20s %-15s %-15s %s\n",
-			key, row.token, row.nullable, row.isFinal,
-			intSliceToString(row.firstPos),
-			intSliceToString(row.lastPos),
-			intSliceToString(row.followPos),
-			actions)
-	}
-}
-
-func printStateSetTable(states []*nodeSet, transitionTokens []string) {
-	// Print header
-	fmt.Printf("%-5s | %-10s | %-7s| %-30s", "ID", "Value", "isFinal", "Action")
-	for _, token := range transitionTokens {
-		fmt.Printf(" | %-10s", token)
-	}
-	fmt.Println("\n" + strings.Repeat("-", 53+12*len(transitionTokens)))
-
-	// Print rows
-	for _, state := range states {
-		// Convert value slice to a comma-separated string
-		valueStr := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(state.value)), ","), "[]")
-		actions := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(state.actions)), ","), "[]")
-
-		// Print ID, Value, and isFinal
-		fmt.Printf("%-5d | %-10s | %-7t| %-30v", state.id, valueStr, state.isFinal, actions)
-
-		// Print transitions
-		for _, token := range transitionTokens {
-			if nextState, exists := state.transitions[token]; exists {
-				fmt.Printf(" | %-10d", nextState.id)
-			} else {
-				fmt.Printf(" | %-10s", "-")
-			}
-		}
-		fmt.Println()
-	}
-}
-
-func PrintDFA(dfa *DFA) {
-	fmt.Println("DFA Representation:")
-	fmt.Println("===================")
-	fmt.Printf("Start State: %s\n\n", dfa.StartState.Id)
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
 
-	for _, state := range dfa.States {
-		fmt.Printf("State: %s\n", state.Id)
-		if state.IsFinal {
-			fmt.Println("  [Final State]")
-		}
-		if len(state.Actions) > 0 {
-			fmt.Println("  Actions:")
-			for _, action := range state.Actions {
-				fmt.Printf("    - Code: %s (Priority: %d)\n", action.Code, action.Priority)
-			}
-		}
-		if len(state.Transitions) > 0 {
-			fmt.Println("  Transitions:")
-			for symbol, target := range state.Transitions {
-				fmt.Printf("    - %s -> %s\n", symbol, target.Id)
-			}
-		}
-		fmt.Println(strings.Repeat("-", 25))
+// Returns the postfix expresion as a string. Ex: "ab·10·"
+func PostfixString(expresion []postfix.Symbol) string {
+	var sb strings.Builder
+	for _, symbol := range expresion {
+		sb.WriteString(symbol.Value)
 	}
+	return sb.String()
 }
 
 // GenerateDOTFromRoot creates a DOT graph from a root Node and saves it as an image
@@ -93,9 +26,6 @@ func RenderAST(root node, outputPath string) error {
 	// Generate the DOT representation
 	dot := GenerateDOT_AST(root)
 
-	// Print the DOT representation (for debugging purposes)
-	// fmt.Println(dot)
-
 	// Generate the image from the DOT representation
 	return GenerateImage(dot, outputPath)
 }
@@ -117,9 +47,7 @@ func GenerateDOT_AST(root node) string {
 	addNode = func(n node, parentID string) string {
 		nodeID := fmt.Sprintf("node%d", nodeCount)
 		nodeCount++
-		nodeLabel := strings.ReplaceAll(n.Value, "\"", "\\\"")
-
-		buf.WriteString(fmt.Sprintf("  %s [label=\"%s\"];\n", nodeID, nodeLabel))
+		buf.WriteString(fmt.Sprintf("  %s [label=%s];\n", nodeID, strconv.Quote(n.Value)))
 
 		if parentID != "" {
 			buf.WriteString(fmt.Sprintf("  %s -> %s;\n", parentID, nodeID))
@@ -140,6 +68,7 @@ func GenerateDOT_AST(root node) string {
 }
 
 // GenerateDOT generates a DOT representation of a DFA as a string.
+// A DFA without states is an empty digraph.
 func GenerateDOT_DFA(dfa *DFA) string {
 	var sb strings.Builder
 
@@ -147,9 +76,9 @@ func GenerateDOT_DFA(dfa *DFA) string {
 	sb.WriteString("digraph DFA {\n")
 	sb.WriteString("    rankdir=LR;\n") // Left to right orientation
 
-	// Check if the DFA has any states
 	if len(dfa.States) == 0 {
-		panic("DFA has no states defined.")
+		sb.WriteString("}\n")
+		return sb.String()
 	}
 
 	// Define the nodes (states)
@@ -162,9 +91,13 @@ func GenerateDOT_DFA(dfa *DFA) string {
 
 		// Define the transitions
 
-		for symbol, toState := range state.Transitions {
-			sb.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [label=\"%s\"];\n",
-				state.Id, toState.Id, symbol))
+		for _, symbol := range SortedSymbols(state.Transitions) {
+			sb.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [label=%s];\n",
+				state.Id, state.Transitions[symbol].Id, strconv.Quote(symbol)))
+		}
+		for _, r := range state.Ranges {
+			sb.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [label=%s];\n",
+				state.Id, r.State.Id, strconv.Quote(formatRange(r))))
 		}
 
 	}
@@ -178,14 +111,6 @@ func GenerateDOT_DFA(dfa *DFA) string {
 	return sb.String()
 }
 
-// getShape returns the shape for the state node based on whether it's a final state.
-func getShape(isFinal bool) string {
-	if isFinal {
-		return "doublecircle"
-	}
-	return "circle"
-}
-
 // GenerateImage generates an image from the DOT representation using Graphviz
 func GenerateImage(dot string, outputPath string) error {
 	cmd := exec.Command("dot", "-Tpng", "-o", outputPath)
@@ -194,3 +119,16 @@ func GenerateImage(dot string, outputPath string) error {
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+// Returns a human readable version of a range transition. Ex: "[a-z]"
+func formatRange(r RangeTransition) string {
+	return fmt.Sprintf("[%s-%s]", printableRune(r.From), printableRune(r.To))
+}
+
+// Returns the rune itself if printable, otherwise its code. Ex: "a", "U+000A"
+func printableRune(r rune) string {
+	if unicode.IsPrint(r) {
+		return string(r)
+	}
+	return fmt.Sprintf("%U", r)
+}
@@ -0,0 +1,384 @@
+package dfa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file writes a DFA in a format other programs can load, so it can be cached or
+// executed without generating Go code. There are two encodings of the same content:
+//
+//   - JSON, for humans and for consumers in other languages.
+//   - Binary, more compact and faster to load.
+//
+// Both start with the version of the format. A program reading a version newer than
+// AUTOMATON_VERSION must refuse it, older versions are always read.
+//
+// States are referred to by their index in the list of states.
+//
+//	{
+//	  "version": 1,
+//	  "start": 0,                   // State to start a lexeme
+//	  "lineStart": 0,               // State to start a lexeme at the start of a line
+//	  "rules": [                    // Indexed by priority
+//	    {"action": "return ID", "trailingContext": true, "trailingLength": 1}
+//	  ],
+//	  "states": [
+//	    {
+//	      "id": "0",
+//	      "rule": -1,               // Rule recognized if the lexeme ends here, -1 if none
+//	      "headEnds": [],           // Rules with trailing context "r/s" whose "r" ends here
+//	      "transitions": [          // Intervals of runes, sorted and disjoint
+//	        {"from": 97, "to": 122, "state": 1}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// The binary encoding is the magic "YLXA" followed by the same fields, as varints
+// (encoding/binary) and strings prefixed by their length:
+//
+//	version start lineStart
+//	len(rules) { trailingContext(0|1) trailingLength action }
+//	len(states) { id rule len(headEnds) {headEnd} len(transitions) {from-prevTo to-from state} }
+//
+// where the from of each transition is written as the distance to the to of the
+// previous one (or to 0 for the first), and trailingLength is a signed varint.
+//
+// The lexeme of a rule with trailing context "r/s" ends trailingLength bytes before the
+// end of the match, or where r ended (its head end) if the length of s is not fixed (-1).
+
+// Version of the format written by EncodeJSON and EncodeBinary.
+const AUTOMATON_VERSION = 1
+
+// First bytes of the binary encoding.
+const automatonMagic = "YLXA"
+
+type automatonFile struct {
+	Version   int              `json:"version"`
+	Start     int              `json:"start"`
+	LineStart int              `json:"lineStart"`
+	Rules     []automatonRule  `json:"rules"`
+	States    []automatonState `json:"states"`
+}
+
+type automatonRule struct {
+	Action          string `json:"action"`
+	TrailingContext bool   `json:"trailingContext"`
+	TrailingLength  int    `json:"trailingLength"`
+}
+
+type automatonState struct {
+	Id          string                `json:"id"`
+	Rule        int                   `json:"rule"`
+	HeadEnds    []int                 `json:"headEnds"`
+	Transitions []automatonTransition `json:"transitions"`
+}
+
+type automatonTransition struct {
+	From  rune `json:"from"`
+	To    rune `json:"to"`
+	State int  `json:"state"`
+}
+
+// Writes the DFA as JSON.
+func EncodeJSON(automata *DFA) ([]byte, error) {
+	file, err := newAutomatonFile(automata)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Writes the DFA in the binary encoding.
+func EncodeBinary(automata *DFA) ([]byte, error) {
+	file, err := newAutomatonFile(automata)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte(automatonMagic)
+	data = binary.AppendUvarint(data, uint64(file.Version))
+	data = binary.AppendUvarint(data, uint64(file.Start))
+	data = binary.AppendUvarint(data, uint64(file.LineStart))
+	data = binary.AppendUvarint(data, uint64(len(file.Rules)))
+	for _, rule := range file.Rules {
+		trailingContext := uint64(0)
+		if rule.TrailingContext {
+			trailingContext = 1
+		}
+		data = binary.AppendUvarint(data, trailingContext)
+		data = binary.AppendVarint(data, int64(rule.TrailingLength))
+		data = appendString(data, rule.Action)
+	}
+	data = binary.AppendUvarint(data, uint64(len(file.States)))
+	for _, state := range file.States {
+		data = appendString(data, state.Id)
+		data = binary.AppendVarint(data, int64(state.Rule))
+		data = binary.AppendUvarint(data, uint64(len(state.HeadEnds)))
+		for _, rule := range state.HeadEnds {
+			data = binary.AppendUvarint(data, uint64(rule))
+		}
+		data = binary.AppendUvarint(data, uint64(len(state.Transitions)))
+		previous := rune(0)
+		for _, t := range state.Transitions {
+			data = binary.AppendUvarint(data, uint64(t.From-previous))
+			data = binary.AppendUvarint(data, uint64(t.To-t.From))
+			data = binary.AppendUvarint(data, uint64(t.State))
+			previous = t.To
+		}
+	}
+	return data, nil
+}
+
+// Writes the DFA to path, as JSON if its extension is ".json" and in the binary encoding otherwise.
+func WriteAutomaton(automata *DFA, path string) error {
+	encode := EncodeBinary
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		encode = EncodeJSON
+	}
+	data, err := encode(automata)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Reads a DFA written by EncodeJSON or EncodeBinary, the encoding is detected from its content.
+func Decode(data []byte) (*DFA, error) {
+	var file automatonFile
+	if bytes.HasPrefix(data, []byte(automatonMagic)) {
+		reader := &binaryReader{data: data[len(automatonMagic):]}
+		file = reader.automatonFile()
+		if reader.err != nil {
+			return nil, fmt.Errorf("reading automaton: %w", reader.err)
+		}
+	} else if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading automaton: %w", err)
+	}
+	return file.dfa()
+}
+
+func newAutomatonFile(automata *DFA) (automatonFile, error) {
+	index := make(map[*State]int, len(automata.States))
+	for i, state := range automata.States {
+		index[state] = i
+	}
+	stateIndex := func(state *State) (int, error) {
+		i, exist := index[state]
+		if !exist {
+			return 0, fmt.Errorf("state %s is not in the states of the DFA", state.Id)
+		}
+		return i, nil
+	}
+
+	file := automatonFile{Version: AUTOMATON_VERSION, Rules: make([]automatonRule, len(automata.Rules))}
+	var err error
+	if file.Start, err = stateIndex(automata.StartState); err != nil {
+		return file, err
+	}
+	file.LineStart = file.Start
+	if automata.LineStartState != nil {
+		if file.LineStart, err = stateIndex(automata.LineStartState); err != nil {
+			return file, err
+		}
+	}
+	for i, rule := range automata.Rules {
+		file.Rules[i] = automatonRule{Action: rule.Code, TrailingContext: rule.TrailingContext, TrailingLength: rule.TrailingLength}
+	}
+	for _, state := range automata.States {
+		s := automatonState{Id: state.Id, Rule: -1, HeadEnds: make([]int, 0), Transitions: make([]automatonTransition, 0)}
+		if state.IsFinal {
+			s.Rule = state.Rule
+		}
+		s.HeadEnds = append(s.HeadEnds, state.HeadEnds...)
+		for _, transition := range runeTransitions(state) {
+			next, err := stateIndex(transition.State)
+			if err != nil {
+				return file, err
+			}
+			s.Transitions = append(s.Transitions, automatonTransition{From: transition.From, To: transition.To, State: next})
+		}
+		file.States = append(file.States, s)
+	}
+	return file, nil
+}
+
+// Builds the DFA of the file, checking that it is well formed.
+func (file automatonFile) dfa() (*DFA, error) {
+	if file.Version < 1 || file.Version > AUTOMATON_VERSION {
+		return nil, fmt.Errorf("automaton version %d is not supported, the latest is %d", file.Version, AUTOMATON_VERSION)
+	}
+	validState := func(i int) bool { return 0 <= i && i < len(file.States) }
+	validRule := func(i int) bool { return 0 <= i && i < len(file.Rules) }
+	if !validState(file.Start) || !validState(file.LineStart) {
+		return nil, fmt.Errorf("automaton start states %d and %d must be within its %d states", file.Start, file.LineStart, len(file.States))
+	}
+
+	automata := &DFA{Rules: make([]Action, len(file.Rules)), States: make([]*State, len(file.States))}
+	for priority, rule := range file.Rules {
+		if rule.TrailingLength < -1 {
+			return nil, fmt.Errorf("automaton rule %d has a trailing context of %d bytes", priority, rule.TrailingLength)
+		}
+		automata.Rules[priority] = Action{Code: rule.Action, Priority: priority, TrailingContext: rule.TrailingContext, TrailingLength: rule.TrailingLength}
+	}
+	for i := range file.States {
+		automata.States[i] = &State{Transitions: make(map[Symbol]*State)}
+	}
+	for i, s := range file.States {
+		state := automata.States[i]
+		state.Id = s.Id
+		state.Rule = s.Rule
+		state.IsFinal = s.Rule >= 0
+		if state.IsFinal && !validRule(s.Rule) {
+			return nil, fmt.Errorf("automaton state %s recognizes rule %d, but there are %d rules", s.Id, s.Rule, len(file.Rules))
+		}
+		for _, rule := range s.HeadEnds {
+			if !validRule(rule) || !file.Rules[rule].TrailingContext {
+				return nil, fmt.Errorf("automaton state %s ends the head of rule %d, which has no trailing context", s.Id, rule)
+			}
+		}
+		if len(s.HeadEnds) > 0 {
+			state.HeadEnds = s.HeadEnds
+		}
+		for j, t := range s.Transitions {
+			if t.From < 0 || t.From > t.To || t.To > utf8.MaxRune {
+				return nil, fmt.Errorf("automaton state %s has a transition over the invalid interval [%d-%d]", s.Id, t.From, t.To)
+			}
+			if j > 0 && t.From <= s.Transitions[j-1].To {
+				return nil, fmt.Errorf("automaton state %s has transitions that are not sorted or overlap", s.Id)
+			}
+			if !validState(t.State) {
+				return nil, fmt.Errorf("automaton state %s moves to state %d, but there are %d states", s.Id, t.State, len(file.States))
+			}
+			next := automata.States[t.State]
+			if t.From == t.To {
+				state.Transitions[string(t.From)] = next
+			} else {
+				state.Ranges = append(state.Ranges, RangeTransition{From: t.From, To: t.To, State: next})
+			}
+		}
+	}
+	automata.StartState = automata.States[file.Start]
+	automata.LineStartState = automata.States[file.LineStart]
+	return automata, nil
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(s)))
+	return append(data, s...)
+}
+
+// Reads the fields of the binary encoding. After the first error it reads zeros and
+// keeps the error.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+var errTruncated = errors.New("the binary automaton ends unexpectedly")
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+// Reads a length or count, which can't be larger than the remaining data.
+func (r *binaryReader) length() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		r.err = errTruncated
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) string() string {
+	n := r.length()
+	if r.err != nil {
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+// Reads a rune or an index, which must fit in an int32.
+func (r *binaryReader) int() int {
+	n := r.uvarint()
+	if n > utf8.MaxRune && r.err == nil {
+		r.err = fmt.Errorf("the binary automaton has the value %d out of range", n)
+	}
+	return int(n)
+}
+
+func (r *binaryReader) automatonFile() automatonFile {
+	// The version is read first, the rest of the layout depends on it
+	file := automatonFile{Version: r.int()}
+	if r.err != nil || file.Version > AUTOMATON_VERSION {
+		return file
+	}
+	file.Start = r.int()
+	file.LineStart = r.int()
+	file.Rules = make([]automatonRule, r.length())
+	for i := range file.Rules {
+		file.Rules[i].TrailingContext = r.uvarint() == 1
+		file.Rules[i].TrailingLength = int(r.varint())
+		file.Rules[i].Action = r.string()
+	}
+	file.States = make([]automatonState, r.length())
+	for i := range file.States {
+		state := &file.States[i]
+		state.Id = r.string()
+		state.Rule = int(max(r.varint(), -1))
+		state.HeadEnds = make([]int, r.length())
+		for j := range state.HeadEnds {
+			state.HeadEnds[j] = r.int()
+		}
+		state.Transitions = make([]automatonTransition, r.length())
+		previous := 0
+		for j := range state.Transitions {
+			t := &state.Transitions[j]
+			t.From = rune(previous + r.int())
+			t.To = t.From + rune(r.int())
+			t.State = r.int()
+			previous = int(t.To)
+		}
+	}
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("the binary automaton has %d bytes after its end", len(r.data))
+	}
+	return file
+}
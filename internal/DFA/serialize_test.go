@@ -0,0 +1,95 @@
+package dfa
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestEncodedDFAIsLoadedBack(t *testing.T) {
+	ruleSets := [][]string{
+		{"if", "[a-z]+", "[0-9]+", " +"},
+		{"^á+", "á/b", "ab$", "[^a]"},
+		{`\p{Greek}+`, "🙂+", "(?i:straße)"},
+	}
+	alphabet := []rune("abifá01 \nλΩßS🙂")
+	random := rand.New(rand.NewSource(44))
+
+	for _, patterns := range ruleSets {
+		rules := make([]postfix.Regex, len(patterns))
+		for i, pattern := range patterns {
+			rules[i] = ruleRegex(t, i, pattern)
+		}
+		automata, numFinalSymbols, err := NewDFA(postfix.Alt{Options: rules}, DIRECT_CONSTRUCTION, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RemoveAbsortionStates(automata, numFinalSymbols)
+
+		for _, encode := range []func(*DFA) ([]byte, error){EncodeJSON, EncodeBinary} {
+			data, err := encode(automata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Decode(data)
+			if err != nil {
+				t.Fatalf("%v: %v", patterns, err)
+			}
+			if !reflect.DeepEqual(loaded.Rules, automata.Rules) {
+				t.Errorf("%v: loaded rules %v, expected %v", patterns, loaded.Rules, automata.Rules)
+			}
+			if len(loaded.States) != len(automata.States) || loaded.StartState.Id != automata.StartState.Id || loaded.LineStartState.Id != automata.LineStartState.Id {
+				t.Fatalf("%v: loaded %d states starting at %s, expected %d starting at %s", patterns, len(loaded.States), loaded.StartState.Id, len(automata.States), automata.StartState.Id)
+			}
+
+			for range 200 {
+				input := make([]rune, random.Intn(10))
+				for i := range input {
+					input[i] = alphabet[random.Intn(len(alphabet))]
+				}
+				if expected, got := trace(automata.StartState, input), trace(loaded.StartState, input); !slices.Equal(expected, got) {
+					t.Errorf("%v on %q: original %v, loaded %v", patterns, string(input), expected, got)
+				}
+				if expected, got := trace(automata.LineStartState, input), trace(loaded.LineStartState, input); !slices.Equal(expected, got) {
+					t.Errorf("%v on %q at line start: original %v, loaded %v", patterns, string(input), expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeRejectsInvalidAutomata(t *testing.T) {
+	automata, _, err := NewDFA(ruleRegex(t, 0, "[a-z]+"), DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := EncodeBinary(automata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`{"version": 2, "states": []}`, "version 2 is not supported"},
+		{`{"version": 1, "rules": [{"action": "", "trailingContext": true, "trailingLength": -2}], "states": [{"id": "0", "rule": -1}]}`, "trailing context of -2 bytes"},
+		{`{"version": 1, "start": 1, "states": [{"id": "0", "rule": -1}]}`, "must be within its 1 states"},
+		{`{"version": 1, "states": [{"id": "0", "rule": 0}]}`, "recognizes rule 0, but there are 0 rules"},
+		{`{"version": 1, "states": [{"id": "0", "rule": -1, "transitions": [{"from": 98, "to": 97, "state": 0}]}]}`, "invalid interval"},
+		{`{"version": 1, "states": [{"id": "0", "rule": -1, "transitions": [{"from": 97, "to": 98, "state": 0}, {"from": 98, "to": 99, "state": 0}]}]}`, "overlap"},
+		{`{"version": 1, "states": [{"id": "0", "rule": -1, "transitions": [{"from": 97, "to": 97, "state": 3}]}]}`, "moves to state 3"},
+		{`{"version": 1, "rules": [{"action": ""}], "states": [{"id": "0", "rule": -1, "headEnds": [0]}]}`, "has no trailing context"},
+		{string(binary[:len(binary)-1]), "ends unexpectedly"},
+		{string(binary) + "x", "1 bytes after its end"},
+	}
+	for _, test := range tests {
+		if _, err := Decode([]byte(test.data)); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("decoding %q: error %v, expected %q", test.data, err, test.expected)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package dfa
 
-import "fmt"
+import (
+	"fmt"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
 
 type Symbol = string
 
@@ -9,41 +13,107 @@ type Symbol = string
 // =====================
 
 type DFA struct {
-	StartState *State
-	States     []*State
+	StartState     *State
+	LineStartState *State // Start state when the input is at the start of a line. Same as StartState if no rule uses "^"
+	States         []*State
+	Rules          []Action // Actions of all rules, indexed by priority
 }
 
 type State struct {
 	Id          string
-	Actions     []Action          // Sorted by highest too lower priority ( 0 has the hightes priority )
-	Transitions map[Symbol]*State // {"a": STATE1, "b": STATE2, "NUMBER": STATEFINAL}
-	IsFinal     bool
+	Rule        int               // Rule (by priority) recognized if the lexeme ends in this state, the one with highest priority. -1 if none
+	Transitions map[Symbol]*State // {"a": STATE1, "b": STATE2}
+	Ranges      []RangeTransition // Transitions over intervals of more than one rune, sorted by From
+	HeadEnds    []int             // Rules (by priority) with trailing context "r/s" whose "r" ends in this state
+	IsFinal     bool              // If a rule is recognized when the lexeme ends in this state
+}
+
+// Transition to State with any rune within the inclusive interval [From, To]
+type RangeTransition struct {
+	From  rune
+	To    rune
+	State *State
 }
 
 type Action struct {
-	Code     string
-	Priority int
+	Code            string
+	Priority        int
+	TrailingContext bool // If the rule has a trailing context "r/s", so the lexeme ends before s
+	TrailingLength  int  // Bytes (in UTF-8) of s for rules with trailing context, -1 if its length is not fixed
+}
+
+// Algorithm used to build the DFA from the regex of all rules.
+type Construction string
+
+const (
+	DIRECT_CONSTRUCTION   Construction = "direct"   // Followpos of the syntax tree positions
+	THOMPSON_CONSTRUCTION Construction = "thompson" // Thompson NFA and subset construction
+)
+
+// Row of the position table, for a leaf of the syntax tree.
+type PositionRow struct {
+	Position  int    `json:"position"`
+	Symbol    string `json:"symbol"`
+	Rule      int    `json:"rule"` // Rule (by priority) the position belongs to, -1 for the end "#"
+	Nullable  bool   `json:"nullable"`
+	FirstPos  []int  `json:"firstPos"`
+	LastPos   []int  `json:"lastPos"`
+	FollowPos []int  `json:"followPos"`
 }
 
 // Table for storing lastpost, first post and follow post for each node in the tree.
 type positionTableRow struct {
 	token     string
+	ranges    []postfix.RuneRange // Runes matched by the token, nil for action symbols
+	anchor    postfix.Anchor      // Anchors do not match any rune, they mark a position in a rule
 	nullable  bool
 	isFinal   bool
 	firstPos  []int
 	lastPos   []int
-	followPos []int
+	followPos bitset
 	action    Action
 }
 
+// Symbol of the alphabet the DFA transitions with. The characters of all patterns
+// are split in disjoint intervals, so every position either matches the whole
+// interval or none of it. Ex: "[a-z]" and "b" => {a}, {b}, {c-z}
+type alphabetSymbol struct {
+	token    string // Key used on transitions
+	from     rune
+	to       rune
+	isAction bool // Action symbols are matched by token instead of interval
+}
+
 // FIXME:
 // Representation of Node with transition
 type nodeSet struct {
 	id          int
-	value       []int
+	value       bitset // Positions (or NFA states) of the set
 	transitions map[string]*nodeSet
 	isFinal     bool
 	actions     []Action
+	headEnds    []int
+}
+
+// =====================
+//	  NFA
+// =====================
+
+// State of a Thompson NFA. It has epsilon transitions, and at most one transition
+// with the symbol of the expresion at position.
+type nfaState struct {
+	id       int
+	epsilon  []*nfaState
+	position int              // Position of the symbol within the postfix expresion, -1 if none
+	symbol   positionTableRow // Token, ranges, anchor and action of the symbol
+	next     *nfaState        // State reached with the symbol
+	isFinal  bool
+}
+
+// Part of a NFA with a single start and end, built for a subexpresion.
+type nfaFragment struct {
+	start *nfaState
+	end   *nfaState
 }
 
 // =====================
@@ -67,6 +137,10 @@ type node struct {
 	IsFinal bool
 	// For special Symbols encapsulate logic to execute when a pattern is meet
 	Action Action
+	// Runes matched by this leaf. Nil for operators, action symbols and anchors.
+	Ranges []postfix.RuneRange
+	// For leafs that mark a position instead of matching runes.
+	Anchor postfix.Anchor
 }
 
 func (n node) String() string {
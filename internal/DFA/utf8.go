@@ -0,0 +1,251 @@
+package dfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file compiles a DFA over runes into a DFA over the bytes of their UTF-8 encoding,
+// so a lexer can move through it one byte at a time without decoding runes.
+// Each interval of runes is split in sequences of byte intervals, as RE2 does:
+//
+//	[a-ÿ] => [61-7F] | [C2-C3][80-BF]
+//
+// The states in the middle of the encoding of a rune are shared between all the
+// states of the DFA that move with the same bytes to the same state.
+
+// DFA that moves with the bytes of the UTF-8 encoding of the input.
+type ByteDFA struct {
+	StartState     *ByteState
+	LineStartState *ByteState // Start state when the input is at the start of a line
+	States         []*ByteState
+	Rules          []Action // Actions of all rules, indexed by priority
+	Classes        [256]int // Equivalence class of each byte, bytes of the same class move to the same states
+	NumClasses     int
+}
+
+type ByteState struct {
+	Id          string
+	Rule        int   // Rule (by priority) recognized if the lexeme ends in this state. -1 if none
+	HeadEnds    []int // Rules (by priority) with trailing context "r/s" whose "r" ends in this state
+	IsFinal     bool
+	Partial     bool             // If it is in the middle of the encoding of a rune
+	Transitions []ByteTransition // Sorted by From, without overlapping
+}
+
+// Transition to State with any byte within the inclusive interval [From, To]
+type ByteTransition struct {
+	From  byte
+	To    byte
+	State *ByteState
+}
+
+// Interval of bytes, part of the encoding of an interval of runes.
+type byteRange struct {
+	from byte
+	to   byte
+}
+
+// Node of the tree of byte sequences leaving a state, before the states in the
+// middle of runes are shared.
+type byteNode struct {
+	edges []byteEdge
+}
+
+type byteEdge struct {
+	bytes  byteRange
+	node   *byteNode  // Next node, nil if the edge ends a rune
+	target *ByteState // State reached at the end of the rune
+}
+
+// Compiles the DFA into a DFA over bytes. The states of the DFA keep its ids,
+// the states in the middle of runes are numbered after them.
+func CompileUTF8(automata *DFA) *ByteDFA {
+	byteDFA := &ByteDFA{Rules: automata.Rules}
+	states := make(map[*State]*ByteState, len(automata.States))
+	for _, state := range automata.States {
+		byteState := &ByteState{Id: state.Id, Rule: state.Rule, HeadEnds: state.HeadEnds, IsFinal: state.IsFinal}
+		states[state] = byteState
+		byteDFA.States = append(byteDFA.States, byteState)
+	}
+	byteDFA.StartState = states[automata.StartState]
+	byteDFA.LineStartState = byteDFA.StartState
+	if automata.LineStartState != nil {
+		byteDFA.LineStartState = states[automata.LineStartState]
+	}
+
+	// States in the middle of runes, by its transitions
+	partialStates := make(map[string]*ByteState)
+	for _, state := range automata.States {
+		root := &byteNode{}
+		for _, transition := range runeTransitions(state) {
+			target := states[transition.State]
+			for _, sequence := range utf8Sequences(transition.From, transition.To) {
+				root.add(sequence, target)
+			}
+		}
+		states[state].Transitions = byteDFA.transitions(root, partialStates)
+	}
+
+	byteDFA.Classes, byteDFA.NumClasses = byteClasses(byteDFA.States)
+	return byteDFA
+}
+
+// Returns all the transitions of a state as intervals of runes, sorted.
+func runeTransitions(state *State) []RangeTransition {
+	transitions := make([]RangeTransition, 0, len(state.Transitions)+len(state.Ranges))
+	for symbol, next := range state.Transitions {
+		r, _ := utf8.DecodeRuneInString(symbol)
+		transitions = append(transitions, RangeTransition{From: r, To: r, State: next})
+	}
+	transitions = append(transitions, state.Ranges...)
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].From < transitions[j].From })
+	return transitions
+}
+
+// Adds a sequence of byte intervals that ends in target. Sequences of disjoint
+// intervals of runes either start with the same byte or with disjoint intervals.
+func (n *byteNode) add(sequence []byteRange, target *ByteState) {
+	if len(sequence) == 1 {
+		n.edges = append(n.edges, byteEdge{bytes: sequence[0], target: target})
+		return
+	}
+	for _, edge := range n.edges {
+		if edge.bytes == sequence[0] && edge.node != nil {
+			edge.node.add(sequence[1:], target)
+			return
+		}
+	}
+	next := &byteNode{}
+	n.edges = append(n.edges, byteEdge{bytes: sequence[0], node: next})
+	next.add(sequence[1:], target)
+}
+
+// Returns the transitions of a node, creating the states of its children in the
+// middle of runes. Children with the same transitions are the same state.
+func (d *ByteDFA) transitions(n *byteNode, partialStates map[string]*ByteState) []ByteTransition {
+	transitions := make([]ByteTransition, 0, len(n.edges))
+	for _, edge := range n.edges {
+		target := edge.target
+		if edge.node != nil {
+			target = d.partialState(d.transitions(edge.node, partialStates), partialStates)
+		}
+		transitions = append(transitions, ByteTransition{From: edge.bytes.from, To: edge.bytes.to, State: target})
+	}
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].From < transitions[j].From })
+	return transitions
+}
+
+// Returns the state in the middle of a rune with the given transitions, creating it
+// the first time.
+func (d *ByteDFA) partialState(transitions []ByteTransition, partialStates map[string]*ByteState) *ByteState {
+	var key strings.Builder
+	for _, t := range transitions {
+		fmt.Fprintf(&key, "%x-%x:%s,", t.From, t.To, t.State.Id)
+	}
+	if state, exist := partialStates[key.String()]; exist {
+		return state
+	}
+	state := &ByteState{
+		Id:          fmt.Sprintf("%d", len(d.States)),
+		Rule:        -1,
+		Partial:     true,
+		Transitions: transitions,
+	}
+	partialStates[key.String()] = state
+	d.States = append(d.States, state)
+	return state
+}
+
+// Splits the bytes the states move with in classes: the bytes that are always
+// in the same intervals. Ex: [a-z] and b => {0-60, 7B-FF}, {61, 63-7A}, {62}
+func byteClasses(states []*ByteState) ([256]int, int) {
+	// Bytes that start a new interval
+	var boundaries [257]bool
+	for _, state := range states {
+		for _, t := range state.Transitions {
+			boundaries[t.From] = true
+			boundaries[int(t.To)+1] = true
+		}
+	}
+	// Bytes of the same interval between boundaries are of the same class. Different
+	// intervals with the same transitions in all states are also the same class.
+	var classes [256]int
+	classOf := make(map[string]int)
+	signature := ""
+	for b := 0; b < 256; b++ {
+		if b == 0 || boundaries[b] {
+			var key strings.Builder
+			for _, state := range states {
+				key.WriteString(state.next(byte(b)) + ",")
+			}
+			signature = key.String()
+			if _, exist := classOf[signature]; !exist {
+				classOf[signature] = len(classOf)
+			}
+		}
+		classes[b] = classOf[signature]
+	}
+	return classes, len(classOf)
+}
+
+// Returns the id of the state reached with b, "" if there is no transition.
+func (s *ByteState) next(b byte) string {
+	i := sort.Search(len(s.Transitions), func(i int) bool { return s.Transitions[i].To >= b })
+	if i < len(s.Transitions) && s.Transitions[i].From <= b {
+		return s.Transitions[i].State.Id
+	}
+	return ""
+}
+
+// Returns the sequences of byte intervals that encode the runes in [from, to].
+// Every byte of a sequence can be combined with every byte of the others.
+// Ex: [U+0080-U+07FF] => [C2-DF][80-BF]
+func utf8Sequences(from, to rune) [][]byteRange {
+	return appendUTF8Sequences(make([][]byteRange, 0), from, to)
+}
+
+func appendUTF8Sequences(result [][]byteRange, from, to rune) [][]byteRange {
+	if from > to {
+		return result
+	}
+	// Surrogates have no encoding
+	if from <= 0xDFFF && to >= 0xD800 {
+		result = appendUTF8Sequences(result, from, min(to, 0xD7FF))
+		return appendUTF8Sequences(result, max(from, 0xE000), to)
+	}
+	// Both ends must be encoded with the same number of bytes
+	for _, last := range []rune{0x7F, 0x7FF, 0xFFFF} {
+		if from <= last && to > last {
+			result = appendUTF8Sequences(result, from, last)
+			return appendUTF8Sequences(result, last+1, to)
+		}
+	}
+	if to <= 0x7F {
+		return append(result, []byteRange{{from: byte(from), to: byte(to)}})
+	}
+	// The continuation bytes after the first one that differs must cover all their values,
+	// [80-BF], so the ends of the interval are split where they don't.
+	for i := 1; i < utf8.UTFMax; i++ {
+		mask := rune(1)<<(6*i) - 1
+		if from&^mask != to&^mask {
+			if from&mask != 0 {
+				result = appendUTF8Sequences(result, from, from|mask)
+				return appendUTF8Sequences(result, (from|mask)+1, to)
+			}
+			if to&mask != mask {
+				result = appendUTF8Sequences(result, from, to&^mask-1)
+				return appendUTF8Sequences(result, to&^mask, to)
+			}
+		}
+	}
+	fromBytes := utf8.AppendRune(nil, from)
+	toBytes := utf8.AppendRune(nil, to)
+	sequence := make([]byteRange, len(fromBytes))
+	for i := range fromBytes {
+		sequence[i] = byteRange{from: fromBytes[i], to: toBytes[i]}
+	}
+	return append(result, sequence)
+}
@@ -0,0 +1,148 @@
+package dfa
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestUTF8SequencesEncodeTheInterval(t *testing.T) {
+	intervals := [][2]rune{
+		{'a', 'z'},
+		{0, utf8.MaxRune},
+		{0x7F, 0x80},
+		{0x100, 0x2FFF},
+		{0xD000, 0xE0FF},
+		{0x10000, 0x10FFFF},
+		{0x7F5, 0x20A3F},
+	}
+	random := rand.New(rand.NewSource(39))
+
+	for _, interval := range intervals {
+		sequences := utf8Sequences(interval[0], interval[1])
+		for i := range 2000 {
+			// Half of the runes are taken from the interval
+			r := rune(random.Intn(utf8.MaxRune + 1))
+			if i%2 == 0 {
+				r = interval[0] + rune(random.Intn(int(interval[1]-interval[0])+1))
+			}
+			if !utf8.ValidRune(r) {
+				// Surrogates are encoded as U+FFFD
+				continue
+			}
+			inside := interval[0] <= r && r <= interval[1]
+			matches := 0
+			for _, sequence := range sequences {
+				if sequenceMatches(sequence, utf8.AppendRune(nil, r)) {
+					matches++
+				}
+			}
+			if inside && matches != 1 || !inside && matches != 0 {
+				t.Fatalf("%U in [%U-%U] matched by %d sequences of %v", r, interval[0], interval[1], matches, sequences)
+			}
+		}
+	}
+}
+
+func sequenceMatches(sequence []byteRange, encoding []byte) bool {
+	if len(sequence) != len(encoding) {
+		return false
+	}
+	for i, b := range encoding {
+		if b < sequence[i].from || sequence[i].to < b {
+			return false
+		}
+	}
+	return true
+}
+
+// Moves from state with the bytes of r, nil if there is no transition or the
+// encoding ends in the middle of a rune.
+func byteStep(state *ByteState, r rune) *ByteState {
+	for _, b := range utf8.AppendRune(nil, r) {
+		if state = state.step(b); state == nil {
+			return nil
+		}
+	}
+	if state.Partial {
+		return nil
+	}
+	return state
+}
+
+func (s *ByteState) step(b byte) *ByteState {
+	for _, t := range s.Transitions {
+		if t.From <= b && b <= t.To {
+			return t.State
+		}
+	}
+	return nil
+}
+
+// Same as trace for the DFA over bytes.
+func byteTrace(start *ByteState, input []rune) []string {
+	result := make([]string, 0, len(input)+1)
+	state := start
+	for i := 0; state != nil; i++ {
+		result = append(result, fmt.Sprintf("%d %v", state.Rule, state.HeadEnds))
+		if i == len(input) {
+			break
+		}
+		state = byteStep(state, input[i])
+	}
+	return result
+}
+
+func TestByteDFAIsEquivalent(t *testing.T) {
+	ruleSets := [][]string{
+		{"if", "[a-z]+", "[0-9]+", " +"},
+		{"ñandú", "[a-zñ-ú]+", "λ|μ", "."},
+		{"^á+", "á/b", "ab$", "[^a]"},
+		{`\p{Greek}+`, `[[:alpha:]]+`, `\s`, "🙂+"},
+		{"(?i:straße)", "[^\n]"},
+	}
+	alphabet := []rune("abñáúλμΩ01 \nßSsſK🙂ÿࠀ￿")
+	random := rand.New(rand.NewSource(39))
+
+	for _, patterns := range ruleSets {
+		rules := make([]postfix.Regex, len(patterns))
+		for i, pattern := range patterns {
+			rules[i] = ruleRegex(t, i, pattern)
+		}
+		automata, _, err := NewDFA(postfix.Alt{Options: rules}, DIRECT_CONSTRUCTION, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byteDFA := CompileUTF8(automata)
+
+		for _, state := range byteDFA.States {
+			for b := range 256 {
+				if next := state.next(byte(b)); next != "" {
+					// Bytes of the same class move to the same state
+					for c := range 256 {
+						if byteDFA.Classes[c] == byteDFA.Classes[b] && state.next(byte(c)) != next {
+							t.Fatalf("%v: bytes %x and %x are of the same class but move to different states", patterns, b, c)
+						}
+					}
+				}
+			}
+		}
+
+		for range 300 {
+			input := make([]rune, random.Intn(12))
+			for i := range input {
+				input[i] = alphabet[random.Intn(len(alphabet))]
+			}
+			if expected, got := trace(automata.StartState, input), byteTrace(byteDFA.StartState, input); !slices.Equal(expected, got) {
+				t.Errorf("%v on %q: runes %v, bytes %v", patterns, string(input), expected, got)
+			}
+			if expected, got := trace(automata.LineStartState, input), byteTrace(byteDFA.LineStartState, input); !slices.Equal(expected, got) {
+				t.Errorf("%v on %q at line start: runes %v, bytes %v", patterns, string(input), expected, got)
+			}
+		}
+	}
+}
@@ -1,81 +1,55 @@
 package Lex_writer
 
 import (
-	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
 
 	dfa "github.com/DanielRasho/Lexer/internal/DFA"
 	yalexDef "github.com/DanielRasho/Lexer/internal/Generator/YALexReader"
-	io "github.com/DanielRasho/Lexer/internal/IO"
 )
 
 // Creates function to convert into string an ADF in order to fill the LexTemplate.go
-// it also stores the header and footer
+// it also stores the header and footer.
+// The lexer moves through the DFA one byte at a time, so it is compiled over the UTF-8
+// encoding of the runes first.
 func CreateLexTemplateComponentes(yal *yalexDef.YALexDefinition, adf *dfa.DFA) LexTemplate {
 
 	var automata string
 	var transitions string
 	var listaStates []string
 	var returningdfa string
-	var slice []dfa.Action
 
-	for i := range len(adf.States) {
+	byteDFA := dfa.CompileUTF8(adf)
 
-		if len(adf.States[i].Actions) > 0 {
-
-			// Sorts by priority so if it finds priority 0 then appends by index 0
-
-			for _, action := range adf.States[i].Actions {
-				index := action.Priority
-				if index > len(slice) {
-					index = len(slice)
-				}
-
-				slice = append(slice[:index], append([]dfa.Action{action}, slice[index:]...)...)
-			}
-
-			//Adds the initial action
-			actions := "\nactions: []action{ \n"
-
-			//For each action add it in the declared actions,
-			for e := range len(slice) {
-
-				codigo := strings.TrimSpace(slice[e].Code)
-				// fmt.Println(adf.States[i].Id)
-				// fmt.Println(codigo)
-				// fmt.Println(slice)
-
-				if strings.Compare(codigo, "") == 1 {
-					codigo = codigo[1 : len(codigo)-1]
-					actions = actions + " func() int {" + codigo + "\nreturn SKIP_LEXEME } , \n"
-				}
-
-			}
+	// A byte of each class, all of them move to the same state
+	classBytes := make([]byte, byteDFA.NumClasses)
+	for b := 255; b >= 0; b-- {
+		classBytes[byteDFA.Classes[b]] = byte(b)
+	}
 
-			clear(slice)
-			slice = slice[:0]
+	for _, st := range byteDFA.States {
 
-			//Once added actions we can create the state with id state0
-			automata = automata + "state" + adf.States[i].Id + " := &state{id: \"" + adf.States[i].Id + "\" , " + actions + "}, transitions: make(map[Symbol]*state), isFinal: " + strconv.FormatBool(adf.States[i].IsFinal) + "}\n"
-			//Stores the list of states in order to put in the return statement
-			listaStates = append(listaStates, "state"+adf.States[i].Id)
-		} else {
-			//Only if there are no actions
-			automata = automata + "state" + adf.States[i].Id + " := &state{id: \"" + adf.States[i].Id + "\" , transitions: make(map[Symbol]*state), isFinal: " + strconv.FormatBool(adf.States[i].IsFinal) + "}\n"
-			listaStates = append(listaStates, "state"+adf.States[i].Id)
+		// Only final states recognize a rule, the one with highest priority
+		rule := ""
+		if st.IsFinal {
+			rule = "rule: " + strconv.Itoa(st.Rule) + ", "
+		}
+		partial := ""
+		if st.Partial {
+			partial = "partial: true, "
 		}
 
-		// Stores all the transitions that are made for every state
-		for symbol := range adf.States[i].Transitions {
-			transymbol := symbol
-			if strings.Compare(symbol, "\n") == 0 {
-				transymbol = "\\n"
+		//Create the state with id state0
+		automata = automata + "state" + st.Id + " := &state{id: \"" + st.Id + "\" , " + rule + trailingContextFields(st) + partial + "next: make([]*state, " + strconv.Itoa(byteDFA.NumClasses) + "), isFinal: " + strconv.FormatBool(st.IsFinal) + "}\n"
+		//Stores the list of states in order to put in the return statement
+		listaStates = append(listaStates, "state"+st.Id)
+
+		// Stores the transitions with each class of bytes, always in the same order
+		for class, b := range classBytes {
+			if next := nextState(st, b); next != nil {
+				transitions = transitions + "state" + st.Id + ".next[" + strconv.Itoa(class) + "] = state" + next.Id + "\n"
 			}
-			transitions = transitions + "state" + adf.States[i].Id + ".transitions[\"" + transymbol + "\"] = state" + adf.States[i].Transitions[symbol].Id + "\n"
 		}
 
 	}
@@ -90,15 +64,15 @@ func CreateLexTemplateComponentes(yal *yalexDef.YALexDefinition, adf *dfa.DFA) L
 	for numi := range len(listaStates) {
 
 		if numi < 1 {
-			returningdfa = returningdfa + "\nreturn &dfa{ \nstartState: " + listaStates[numi] + ",\nstates: []*state{ " + listaStates[numi] + ", "
+			returningdfa = returningdfa + "\nreturn &dfa{ \nstartState: state" + byteDFA.StartState.Id + ",\nlineStartState: state" + byteDFA.LineStartState.Id + ",\nstates: []*state{ " + listaStates[numi] + ", "
 		} else {
 			returningdfa = returningdfa + listaStates[numi] + ", "
 
 		}
 
 	}
-	//Cierra el return statement
-	returningdfa = returningdfa + "}, \n}"
+	//Cierra la lista de estados y agrega las acciones de cada regla, por prioridad, y la clase de cada byte
+	returningdfa = returningdfa + "},\nrules: []rule{\n" + rulesList(byteDFA.Rules) + "},\nclasses: " + classesArray(byteDFA.Classes) + ", \n}"
 
 	//Se agrega todos los contenidos de la automata y luego regresamos el Lex Templates
 	automata = automata + returningdfa
@@ -111,38 +85,59 @@ func CreateLexTemplateComponentes(yal *yalexDef.YALexDefinition, adf *dfa.DFA) L
 
 }
 
-func FillwithTemplate(filePath string, lextemp LexTemplate, outputfilepath string) {
-
-	//Generate DFA y Remove Abosptions States
-
-	var content string
-	var line string
-	filereader, _ := io.ReadFile(filePath)
-
-	//Para cada linea se va a agregar al wholefile que es para agregar todo el contenido al archivo Go
-	for filereader.NextLine(&line) {
-		content = content + line
+// Returns the fields a state needs for rules with trailing context "r/s":
+// the rules whose "r" ends in it.
+func trailingContextFields(st *dfa.ByteState) string {
+	fields := ""
+	if len(st.HeadEnds) > 0 {
+		rules := make([]string, len(st.HeadEnds))
+		for i, rule := range st.HeadEnds {
+			rules[i] = strconv.Itoa(rule)
+		}
+		fields = fields + "headEnds: []int{" + strings.Join(rules, ", ") + "}, "
 	}
+	return fields
+}
 
-	tmpl, err := template.New("fileTemplate").Parse(content)
-	if err != nil {
-		fmt.Println("Error parsing template:", err)
-		return
+// Returns the definition of each rule: the action to execute when it is recognized
+// and if it has trailing context. Each action is written only once.
+func rulesList(rules []dfa.Action) string {
+	list := ""
+	for _, rule := range rules {
+		codigo := strings.TrimSpace(rule.Code)
+		if codigo != "" {
+			// Remove the braces around the code of the action
+			codigo = codigo[1 : len(codigo)-1]
+		}
+		list = list + "{action: func() int {" + codigo + "\nreturn SKIP_LEXEME }"
+		if rule.TrailingContext {
+			list = list + ", trailingContext: true, trailingLength: " + strconv.Itoa(rule.TrailingLength)
+		}
+		list = list + "},\n"
 	}
+	return list
+}
 
-	outputFile, err := os.Create(outputfilepath)
-	if err != nil {
-		fmt.Println("Error creating output file:", err)
-		return
+// Returns the state reached from st with b, nil if there is no transition.
+func nextState(st *dfa.ByteState, b byte) *dfa.ByteState {
+	for _, t := range st.Transitions {
+		if t.From <= b && b <= t.To {
+			return t.State
+		}
 	}
-	defer outputFile.Close()
+	return nil
+}
 
-	err = tmpl.Execute(outputFile, lextemp)
-	if err != nil {
-		fmt.Println("Error executing template:", err)
-		return
+// Returns the class of each byte as an array, 16 bytes per line.
+func classesArray(classes [256]int) string {
+	array := "[256]uint8{"
+	for b, class := range classes {
+		if b%16 == 0 {
+			array = array + "\n"
+		}
+		array = array + strconv.Itoa(class) + ", "
 	}
-
+	return array + "\n}"
 }
 
 func extractNumber(s string) int {
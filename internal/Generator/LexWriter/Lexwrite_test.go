@@ -3,43 +3,62 @@ package Lex_writer
 // Aceptar cualquier caracter
 
 import (
+	"go/parser"
+	"go/token"
+	"strings"
 	"testing"
 
 	dfa "github.com/DanielRasho/Lexer/internal/DFA"
 	yalexDef "github.com/DanielRasho/Lexer/internal/Generator/YALexReader"
 )
 
-// Se inicializa la tabla y se revisa si el mapa tiene un estado y verificar si ese estado es final
-func Test_check(t *testing.T) {
-
+// Each backend writes the header, the footer and the actions of the definition in the
+// code of its language.
+func TestBackendsWriteTheLexer(t *testing.T) {
 	yal := yalexDef.YALexDefinition{
 		Footer: "//Footings\n\n\n",
 		Header: "//Heading\n\n\n",
 	}
-
 	adf := initializeSimpleDFA()
 
-	lextemp := CreateLexTemplateComponentes(&yal, &adf)
-
-	FillwithTemplate("../../../template/LexTemplate.go", lextemp, "../../examples/OutputTemplate.go")
-
+	expected := map[Target][]string{
+		GO_TARGET:         {"package calc", "func NewLexer(", "rule: 0", "return LITERAL"},
+		C_TARGET:          {"int yylex(void)", "case 0:", "return LITERAL"},
+		TYPESCRIPT_TARGET: {"export class Lexer", "case 0:", "return LITERAL"},
+	}
+	for target, fragments := range expected {
+		var output strings.Builder
+		spec := LexSpec{Definition: &yal, Automata: &adf, Package: "calc"}
+		if err := NewBackend(target).Write(spec, &output); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+		lexer := output.String()
+		for _, fragment := range append(fragments, "//Heading", "//Footings") {
+			if !strings.Contains(lexer, fragment) {
+				t.Errorf("%s: expected %q in the lexer\n%s", target, fragment, lexer)
+			}
+		}
+		if target == GO_TARGET {
+			if _, err := parser.ParseFile(token.NewFileSet(), "lexer.go", lexer, 0); err != nil {
+				t.Errorf("the Go lexer is not valid Go: %v", err)
+			}
+		}
+	}
 }
 
 func initializeSimpleDFA() dfa.DFA {
 	// Define states
 
 	q0 := &dfa.State{
-		Id:      "0",
-		IsFinal: false,
-		Actions: []dfa.Action{
-			{Code: "func() int { return LITERAL}  ", Priority: 0},    // Direct initialization of action1
-			{Code: "func() int { return NO_LEXEME}   ", Priority: 1}, // Direct initialization of action2
-		},
+		Id:          "0",
+		Rule:        -1,
+		IsFinal:     false,
 		Transitions: make(map[dfa.Symbol]*dfa.State),
 	}
 
 	q1 := &dfa.State{
 		Id:          "1",
+		Rule:        0,
 		IsFinal:     true,
 		Transitions: make(map[dfa.Symbol]*dfa.State),
 	}
@@ -54,6 +73,10 @@ func initializeSimpleDFA() dfa.DFA {
 	dfa := dfa.DFA{
 		StartState: q0,
 		States:     []*dfa.State{q0, q1},
+		Rules: []dfa.Action{
+			{Code: "{ return LITERAL}  ", Priority: 0},
+			{Code: "{ return NO_LEXEME}   ", Priority: 1},
+		},
 	}
 
 	return dfa
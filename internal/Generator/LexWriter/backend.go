@@ -0,0 +1,220 @@
+package Lex_writer
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	yalexDef "github.com/DanielRasho/Lexer/internal/Generator/YALexReader"
+)
+
+// Language a lexer is written in.
+type Target string
+
+const (
+	GO_TARGET         Target = "go"
+	C_TARGET          Target = "c"          // yylex, like flex
+	TYPESCRIPT_TARGET Target = "typescript" // A Lexer class, for browsers and Node
+)
+
+// Returns the target of the given name.
+func ParseTarget(name string) (Target, error) {
+	switch target := Target(strings.ToLower(name)); target {
+	case GO_TARGET, C_TARGET, TYPESCRIPT_TARGET:
+		return target, nil
+	case "ts":
+		return TYPESCRIPT_TARGET, nil
+	}
+	return "", fmt.Errorf("unknown target %q, use %q, %q or %q", name, GO_TARGET, C_TARGET, TYPESCRIPT_TARGET)
+}
+
+// Everything a backend needs to write a lexer.
+type LexSpec struct {
+	Definition         *yalexDef.YALexDefinition // Header and footer, written as they are
+	Automata           *dfa.DFA                  // DFA of all rules, with their actions
+	ReplaceInvalidUTF8 bool                      // If invalid UTF-8 is read as U+FFFD, instead of returned as INVALID_UTF8 tokens
+	Package            string                    // Package of the lexer, for the targets that have packages
+}
+
+// Writes lexers in a language. All of them move through the same DFA over the bytes
+// of UTF-8 input, and copy the header, footer and actions as they are.
+type Backend interface {
+	Write(spec LexSpec, output io.Writer) error
+}
+
+// Templates of the lexers of each target, built into the generator so it runs from any directory.
+//
+//go:embed templates
+var templates embed.FS
+
+// Returns the backend of target.
+func NewBackend(target Target) Backend {
+	switch target {
+	case C_TARGET:
+		return tableBackend{template: "templates/LexTemplate.c.tmpl"}
+	case TYPESCRIPT_TARGET:
+		return tableBackend{template: "templates/LexTemplate.ts.tmpl"}
+	}
+	return goBackend{template: "templates/LexTemplate.go.tmpl"}
+}
+
+// Writes the lexer to output, filling the embedded template with the fields of data.
+func executeTemplate(name string, data any, output io.Writer) error {
+	tmpl, err := template.ParseFS(templates, name)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	if err := tmpl.Execute(output, data); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+	return nil
+}
+
+// Writes the DFA as Go code that builds its states.
+type goBackend struct {
+	template string
+}
+
+func (b goBackend) Write(spec LexSpec, output io.Writer) error {
+	lextemp := CreateLexTemplateComponentes(spec.Definition, spec.Automata)
+	lextemp.ReplaceInvalidUTF8 = spec.ReplaceInvalidUTF8
+	lextemp.Package = spec.Package
+	return executeTemplate(b.template, lextemp, output)
+}
+
+// Writes the DFA as arrays, for languages whose template runs the lexer over them.
+type tableBackend struct {
+	template string
+}
+
+func (b tableBackend) Write(spec LexSpec, output io.Writer) error {
+	return executeTemplate(b.template, newLexTables(spec), output)
+}
+
+// Fields of the templates of table backends. The lists are written as numbers
+// separated by commas, so they fit the array syntax of any language.
+type LexTables struct {
+	Header string
+	Footer string
+
+	NumStates      int
+	NumClasses     int
+	NumRules       int
+	StartState     int
+	LineStartState int
+	Classes        string // Class of each byte
+	Next           string // State reached from each state with each class, -1 if none. Index: state*NumClasses + class
+	Rules          string // Rule recognized if the lexeme ends in each state, -1 if none
+	Partial        string // 1 for states in the middle of the UTF-8 encoding of a rune
+	HeadEndsStart  string // Index in HeadEnds where the ones of each state start, and one more with its length
+	HeadEnds       string // Rules with trailing context "r/s" whose "r" ends in each state
+	Trailing       string // 1 for the rules with trailing context, by priority
+	TrailingLength string // Bytes of the trailing context of each rule, -1 if its length is not fixed
+	Actions        []TableAction
+
+	ReplaceInvalidUTF8 bool
+}
+
+// Action of a rule, with its braces.
+type TableAction struct {
+	Rule int
+	Code string
+}
+
+func newLexTables(spec LexSpec) LexTables {
+	byteDFA := dfa.CompileUTF8(spec.Automata)
+	tables := LexTables{
+		Header:             spec.Definition.Header,
+		Footer:             spec.Definition.Footer,
+		NumStates:          len(byteDFA.States),
+		NumClasses:         byteDFA.NumClasses,
+		NumRules:           len(byteDFA.Rules),
+		ReplaceInvalidUTF8: spec.ReplaceInvalidUTF8,
+	}
+
+	// States are referred to by their index
+	index := make(map[*dfa.ByteState]int, len(byteDFA.States))
+	for i, st := range byteDFA.States {
+		index[st] = i
+	}
+	tables.StartState = index[byteDFA.StartState]
+	tables.LineStartState = index[byteDFA.LineStartState]
+
+	next := make([]int, 0, len(byteDFA.States)*byteDFA.NumClasses)
+	rules := make([]int, 0, len(byteDFA.States))
+	partial := make([]int, 0, len(byteDFA.States))
+	headEndsStart := make([]int, 0, len(byteDFA.States)+1)
+	headEnds := make([]int, 0)
+	for _, st := range byteDFA.States {
+		row := make([]int, byteDFA.NumClasses)
+		for class := range row {
+			row[class] = -1
+		}
+		for _, t := range st.Transitions {
+			for b := int(t.From); b <= int(t.To); b++ {
+				row[byteDFA.Classes[b]] = index[t.State]
+			}
+		}
+		next = append(next, row...)
+
+		rule := -1
+		if st.IsFinal {
+			rule = st.Rule
+		}
+		rules = append(rules, rule)
+		partial = append(partial, boolToInt(st.Partial))
+		headEndsStart = append(headEndsStart, len(headEnds))
+		headEnds = append(headEnds, st.HeadEnds...)
+	}
+	headEndsStart = append(headEndsStart, len(headEnds))
+
+	trailing := make([]int, len(byteDFA.Rules))
+	trailingLength := make([]int, len(byteDFA.Rules))
+	for priority, rule := range byteDFA.Rules {
+		trailing[priority] = boolToInt(rule.TrailingContext)
+		trailingLength[priority] = rule.TrailingLength
+		code := strings.TrimSpace(rule.Code)
+		if code == "" {
+			code = "{}"
+		}
+		tables.Actions = append(tables.Actions, TableAction{Rule: priority, Code: code})
+	}
+
+	tables.Classes = numberList(byteDFA.Classes[:], 16)
+	tables.Next = numberList(next, byteDFA.NumClasses)
+	tables.Rules = numberList(rules, 16)
+	tables.Partial = numberList(partial, 16)
+	tables.HeadEndsStart = numberList(headEndsStart, 16)
+	tables.HeadEnds = numberList(headEnds, 16)
+	tables.Trailing = numberList(trailing, 16)
+	tables.TrailingLength = numberList(trailingLength, 16)
+	return tables
+}
+
+// Returns the numbers separated by commas, perLine numbers per line.
+func numberList(numbers []int, perLine int) string {
+	var list strings.Builder
+	for i, n := range numbers {
+		if i > 0 {
+			list.WriteString(",")
+			if i%perLine == 0 {
+				list.WriteString("\n")
+			} else {
+				list.WriteString(" ")
+			}
+		}
+		list.WriteString(strconv.Itoa(n))
+	}
+	return list.String()
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
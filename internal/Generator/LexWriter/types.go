@@ -7,4 +7,7 @@ type LexTemplate struct {
 	Header   string
 	Automata string
 	Footer   string
+	Package  string // Package of the lexer
+
+	ReplaceInvalidUTF8 bool // If invalid UTF-8 is read as U+FFFD, instead of returned as INVALID_UTF8 tokens
 }
@@ -0,0 +1,127 @@
+package report
+
+// This module writes a static HTML page that explains how the lexer recognizes tokens:
+// the syntax tree of each rule, the position table, the DFA, and a simulator of the lexer
+// that runs in the browser, without network access.
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"sort"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// A rule of the lexer, as written in the YALex file, and the regex built for it.
+type Rule struct {
+	Pattern string
+	Action  string
+	Regex   postfix.Regex // Regex of the rule, ending with its action symbol
+}
+
+// Content of the report page.
+type page struct {
+	Source    string
+	Rules     []pageRule
+	Positions []dfa.PositionRow
+	DFA       template.HTML // SVG image
+	Automaton automaton     // Read by the simulator
+}
+
+type pageRule struct {
+	Rule
+	Priority int
+	AST      template.HTML // SVG image
+}
+
+// DFA as read by the simulator, over runes.
+type automaton struct {
+	Start     string       `json:"start"`
+	LineStart string       `json:"lineStart"`
+	States    []state      `json:"states"`
+	Rules     []ruleAction `json:"rules"`
+}
+
+type state struct {
+	Id          string       `json:"id"`
+	Rule        int          `json:"rule"`
+	HeadEnds    []int        `json:"headEnds"`
+	Transitions []transition `json:"transitions"` // Sorted by from
+}
+
+type transition struct {
+	From  rune   `json:"from"`
+	To    rune   `json:"to"`
+	State string `json:"state"`
+}
+
+type ruleAction struct {
+	Pattern         string `json:"pattern"`
+	TrailingContext bool   `json:"trailingContext"`
+	TrailingLength  int    `json:"trailingLength"`
+}
+
+// Writes the report of the lexer built from the rules of source to path.
+// The automata must be the one the lexer is generated with, and expresion the regex of all rules.
+func Write(path, source string, rules []Rule, expresion postfix.Regex, automata *dfa.DFA) error {
+	positions, err := dfa.PositionTable(expresion)
+	if err != nil {
+		return err
+	}
+
+	content := page{
+		Source:    source,
+		Positions: positions,
+		DFA:       template.HTML(dfa.GenerateSVG_DFA(automata)),
+		Automaton: newAutomaton(rules, automata),
+	}
+	for priority, rule := range rules {
+		ast, err := dfa.ASTDiagram(rule.Regex, dfa.SVG_DIAGRAM)
+		if err != nil {
+			return err
+		}
+		content.Rules = append(content.Rules, pageRule{Rule: rule, Priority: priority, AST: template.HTML(ast)})
+	}
+
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return tmpl.Execute(file, content)
+}
+
+func newAutomaton(rules []Rule, automata *dfa.DFA) automaton {
+	result := automaton{Start: automata.StartState.Id, LineStart: automata.StartState.Id}
+	if automata.LineStartState != nil {
+		result.LineStart = automata.LineStartState.Id
+	}
+	for _, st := range automata.States {
+		s := state{Id: st.Id, Rule: -1, HeadEnds: st.HeadEnds, Transitions: make([]transition, 0)}
+		if st.IsFinal {
+			s.Rule = st.Rule
+		}
+		for _, symbol := range dfa.SortedSymbols(st.Transitions) {
+			r := []rune(symbol)[0]
+			s.Transitions = append(s.Transitions, transition{From: r, To: r, State: st.Transitions[symbol].Id})
+		}
+		for _, r := range st.Ranges {
+			s.Transitions = append(s.Transitions, transition{From: r.From, To: r.To, State: r.State.Id})
+		}
+		sort.Slice(s.Transitions, func(i, j int) bool { return s.Transitions[i].From < s.Transitions[j].From })
+		result.States = append(result.States, s)
+	}
+	for priority, action := range automata.Rules {
+		result.Rules = append(result.Rules, ruleAction{Pattern: rules[priority].Pattern, TrailingContext: action.TrailingContext, TrailingLength: action.TrailingLength})
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestWriteReport(t *testing.T) {
+	patterns := []string{"if", "[a-z]+", `"<"/a`}
+	rules := make([]Rule, len(patterns))
+	regexes := make([]postfix.Regex, len(patterns))
+	for i, pattern := range patterns {
+		rulePattern, err := postfix.ParseRule(pattern, postfix.ParseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		regexes[i] = rulePattern.Regex(postfix.Accept{
+			Value:  string(rune('A' + i)),
+			Action: postfix.Action{Priority: i, Code: "{ return 0 }"},
+		})
+		rules[i] = Rule{Pattern: pattern, Action: "{ return 0 }", Regex: regexes[i]}
+	}
+	expresion := postfix.Alt{Options: regexes}
+	automata, _, err := dfa.NewDFA(expresion, dfa.DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := Write(path, "spec.lex", rules, expresion, automata); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(content)
+
+	// Patterns are escaped
+	if !strings.Contains(html, "<code>&#34;&lt;&#34;/a</code>") {
+		t.Errorf("the report does not show the pattern %q escaped", patterns[2])
+	}
+	// One syntax tree for each rule, and the DFA
+	if count := strings.Count(html, "<svg"); count != len(patterns)+1 {
+		t.Errorf("expected %d images, got %d", len(patterns)+1, count)
+	}
+
+	// The simulator reads the DFA as JSON
+	match := regexp.MustCompile(`const automaton = (.*);`).FindStringSubmatch(html)
+	if match == nil {
+		t.Fatal("the report has no automaton")
+	}
+	var read automaton
+	if err := json.Unmarshal([]byte(match[1]), &read); err != nil {
+		t.Fatal(err)
+	}
+	if len(read.States) != len(automata.States) || len(read.Rules) != len(patterns) || !read.Rules[2].TrailingContext {
+		t.Errorf("automaton does not match the DFA: %+v", read)
+	}
+}
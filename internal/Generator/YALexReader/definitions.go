@@ -0,0 +1,130 @@
+package yalex_reader
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// Named patterns are parsed when a rule refers to them, as "{NAME}". References are
+// resolved by name, so the order of declaration doesn't matter and a name can't be
+// mistaken by another one that starts the same way ({ID} and {IDENT}).
+
+// The named patterns visible to the rules of a definition file.
+type Scope struct {
+	patterns  map[string]*NamedPattern
+	resolved  map[resolvedKey]postfix.Regex // Cache of already parsed patterns
+	resolving []string                      // Names being resolved, to detect cycles
+}
+
+type resolvedKey struct {
+	name            string
+	caseInsensitive bool
+}
+
+func NewScope() *Scope {
+	return &Scope{
+		patterns: make(map[string]*NamedPattern),
+		resolved: make(map[resolvedKey]postfix.Regex),
+	}
+}
+
+// Adds a named pattern to the scope. A name can only be defined once.
+func (s *Scope) Define(name, pattern string) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid name %q for a named pattern, use letters, digits and \"_\"", name)
+	}
+	if _, exist := s.patterns[name]; exist {
+		return fmt.Errorf("named pattern {%s} is already defined", name)
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("named pattern {%s} has an empty pattern", name)
+	}
+	s.patterns[name] = &NamedPattern{Name: name, Pattern: pattern}
+	return nil
+}
+
+// Returns the named pattern with the given name.
+func (s *Scope) Lookup(name string) (*NamedPattern, bool) {
+	pattern, exist := s.patterns[name]
+	return pattern, exist
+}
+
+// Returns the expression of the named pattern, parsed case-insensitive if caseInsensitive.
+// It is the Resolve of postfix.ParseOptions for the rules of the definition file.
+func (s *Scope) Resolve(name string, caseInsensitive bool) (postfix.Regex, error) {
+	key := resolvedKey{name: name, caseInsensitive: caseInsensitive}
+	if regex, exist := s.resolved[key]; exist {
+		return regex, nil
+	}
+
+	for i, visiting := range s.resolving {
+		if visiting == name {
+			cycle := append(slices.Clone(s.resolving[i:]), name)
+			return nil, fmt.Errorf("named patterns refer to themselves: {%s}", strings.Join(cycle, "} -> {"))
+		}
+	}
+	pattern, exist := s.Lookup(name)
+	if !exist {
+		return nil, fmt.Errorf("undefined named pattern {%s}", name)
+	}
+
+	s.resolving = append(s.resolving, name)
+	regex, err := postfix.Parse(pattern.Pattern, postfix.ParseOptions{CaseInsensitive: caseInsensitive, Resolve: s.Resolve})
+	s.resolving = s.resolving[:len(s.resolving)-1]
+	if syntaxErr, isSyntaxErr := err.(*postfix.SyntaxError); isSyntaxErr && syntaxErr.NamedPattern == "" {
+		syntaxErr.NamedPattern = name
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.resolved[key] = regex
+	return regex, nil
+}
+
+// Checks every named pattern can be resolved, even the ones no rule uses.
+func (s *Scope) Validate() error {
+	for _, name := range slices.Sorted(maps.Keys(s.patterns)) {
+		if _, err := s.Resolve(name, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads a line of the named patterns section, either "let NAME = pattern" or "NAME pattern".
+// Returns the name and its pattern.
+func parseNamedPatternLine(line string) (string, string, error) {
+	line = strings.TrimSpace(line)
+
+	if rest, isLet := strings.CutPrefix(line, "let "); isLet {
+		name, pattern, found := strings.Cut(rest, "=")
+		if !found {
+			return "", "", fmt.Errorf("expected \"=\" after the name of the named pattern: %s", line)
+		}
+		return strings.TrimSpace(name), strings.TrimSpace(pattern), nil
+	}
+
+	end := strings.IndexFunc(line, unicode.IsSpace)
+	if end == -1 {
+		return "", "", fmt.Errorf("named pattern without a pattern: %s", line)
+	}
+	return line[:end], strings.TrimSpace(line[end:]), nil
+}
+
+// Names of named patterns start with a letter or "_", followed by letters, digits or "_".
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package yalex_reader
+
+import (
+	"strings"
+	"testing"
+
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+func TestScopeResolve(t *testing.T) {
+	scope := NewScope()
+	definitions := [][2]string{
+		{"ID", "{LETTER}({LETTER}|{DIGIT})*"}, // Used before being defined
+		{"LETTER", "[a-z]"},
+		{"DIGIT", "[0-9]"},
+		{"IDENT", "x"},                       // ID is a prefix of IDENT
+		{"BRACES", `"{DIGIT}"[{DIGIT}]a{2}`}, // Quoted or within a class, it is not a reference
+	}
+	for _, d := range definitions {
+		if err := scope.Define(d[0], d[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := scope.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"ID", "{LETTER}({LETTER}|{DIGIT})*"},
+		{"IDENT", "x"},
+		{"BRACES", `\{DIGIT\}[{DIGIT}]a{2}`},
+	}
+	for _, c := range cases {
+		regex, err := scope.Resolve(c.name, false)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if regex.String() != c.expected {
+			t.Errorf("%s: expected %q got %q", c.name, c.expected, regex.String())
+		}
+	}
+
+	// References hold the expression they stand for
+	regex, _ := scope.Resolve("ID", false)
+	if letter := regex.(postfix.Concat).Items[0].(postfix.Ref); letter.Name != "LETTER" || letter.Expr.String() != "[a-z]" {
+		t.Errorf("expected {LETTER} to be [a-z], got %s", letter.Expr)
+	}
+}
+
+func TestScopeErrors(t *testing.T) {
+	scope := NewScope()
+	scope.Define("A", "a{B}")
+	scope.Define("B", "b{A}")
+	scope.Define("C", "{MISSING}")
+
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"A", "named patterns refer to themselves: {A} -> {B} -> {A}"},
+		{"C", "undefined named pattern {MISSING} at column 1 of named pattern {C}"},
+		{"UNDEFINED", "undefined named pattern {UNDEFINED}"},
+	}
+	for _, c := range cases {
+		if _, err := scope.Resolve(c.name, false); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected the error %q, got %v", c.name, c.expected, err)
+		}
+	}
+	if err := scope.Validate(); err == nil {
+		t.Errorf("expected the scope to be invalid")
+	}
+
+	if err := scope.Define("A", "x"); err == nil {
+		t.Errorf("expected an error redefining A")
+	}
+	if err := scope.Define("1A", "x"); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+}
+
+func TestParseNamedPatternLine(t *testing.T) {
+	cases := []struct {
+		line    string
+		name    string
+		pattern string
+	}{
+		{"    let ID = {LETTER}({LETTER}|{DIGIT})*\n", "ID", "{LETTER}({LETTER}|{DIGIT})*"},
+		{"let EQ = =\n", "EQ", "="},
+		{"\tdigit\t[0-9]\n", "digit", "[0-9]"},
+		{"ws  ([ \\t\\n])+", "ws", `([ \t\n])+`},
+	}
+	for _, c := range cases {
+		name, pattern, err := parseNamedPatternLine(c.line)
+		if err != nil || name != c.name || pattern != c.pattern {
+			t.Errorf("%q: expected (%q, %q) got (%q, %q, %v)", c.line, c.name, c.pattern, name, pattern, err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package yalex_reader
+
+import (
+	"bufio"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// The canonical form of a definition file:
+//   - The header and the footer are formatted with gofmt, or only dedented if they are
+//     not Go code. Comments and blank lines between the sections are kept.
+//   - Named patterns are written as "NAME pattern", indented, with their patterns
+//     starting at the same column.
+//   - Rules have their actions starting at the same column, formatted with gofmt if they
+//     are Go code.
+//   - Comments at the end of a line start at the same column in each group of lines,
+//     which are separated by blank lines. Runs of blank lines are written as one.
+
+// Options of Format.
+type FormatOptions struct {
+	GoCode bool // Format the header, the footer and the actions with gofmt. Code that isn't valid Go is written as it is
+}
+
+const namedPatternIndent = "    "
+
+// Writes the definition read from source to output in its canonical form. The sections
+// are read as Parse reads them, so the result defines the same lexer.
+func Format(source io.Reader, output io.Writer, options FormatOptions) error {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(nil, 1024*1024)
+	f := formatter{options: options, lines: make([]string, 0)}
+
+	const (
+		outside = iota
+		inCode
+		inNamedPatterns
+		inRules
+	)
+	section := outside
+	sectionStart := 0 // Line where the current section starts, for errors
+	rulesSeen := false
+	body := make([]string, 0) // Lines of the current section
+
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case section == outside:
+			switch {
+			case trimmed == "%{":
+				section = inCode
+			case trimmed == "{":
+				section = inNamedPatterns
+			case trimmed == "%%" && !rulesSeen:
+				section = inRules
+				rulesSeen = true
+			default:
+				f.outside(line)
+				continue
+			}
+			sectionStart = number
+			body = body[:0]
+		case section == inCode && trimmed == "%}":
+			f.code(body)
+			section = outside
+		case section == inNamedPatterns && trimmed == "}":
+			f.namedPatterns(body)
+			section = outside
+		case section == inRules && trimmed == "%%":
+			f.rules(body)
+			section = outside
+		default:
+			body = append(body, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	switch section {
+	case inCode:
+		return fmt.Errorf("line %d: %%{ without %%}", sectionStart)
+	case inNamedPatterns:
+		return fmt.Errorf("line %d: { of the named patterns without }", sectionStart)
+	case inRules:
+		f.rules(body) // The parser doesn't need the closing %%
+	}
+
+	writer := bufio.NewWriter(output)
+	for _, line := range f.lines {
+		writer.WriteString(line + "\n")
+	}
+	return writer.Flush()
+}
+
+type formatter struct {
+	options FormatOptions
+	lines   []string // Lines written so far
+}
+
+// Adds a blank line, unless the last one is already blank.
+func (f *formatter) blank() {
+	if len(f.lines) > 0 && f.lines[len(f.lines)-1] != "" {
+		f.lines = append(f.lines, "")
+	}
+}
+
+// Adds a line outside the sections: a comment, an option or a blank line.
+func (f *formatter) outside(line string) {
+	switch trimmed := strings.TrimSpace(line); {
+	case trimmed == "":
+		f.blank()
+	case strings.HasPrefix(trimmed, "%option"):
+		f.lines = append(f.lines, strings.Join(strings.Fields(trimmed), " "))
+	default:
+		f.lines = append(f.lines, line)
+	}
+}
+
+// Adds the header or the footer.
+func (f *formatter) code(body []string) {
+	f.lines = append(f.lines, "%{")
+	code := strings.Join(body, "\n")
+	if formatted, ok := f.formatGo(code); ok {
+		if formatted = strings.Trim(formatted, "\n"); formatted != "" {
+			f.lines = append(f.lines, strings.Split(formatted, "\n")...)
+		}
+	} else {
+		f.lines = append(f.lines, dedent(trimBlankLines(body))...)
+	}
+	f.lines = append(f.lines, "%}")
+}
+
+// Returns the Go declarations of code formatted with gofmt, and false if they are not valid Go.
+// Comments are kept as they are written, instead of reformatted as doc comments.
+func (f *formatter) formatGo(code string) (string, bool) {
+	if !f.options.GoCode {
+		return "", false
+	}
+	source := "package p\n" + code
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", source, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+
+	// gofmt reformats comments at the start of a line before a declaration as doc
+	// comments, so they are indented to be printed as they are
+	lines := strings.Split(source, "\n")
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if position := fileSet.Position(comment.Pos()); position.Column == 1 {
+				lines[position.Line-1] = " " + lines[position.Line-1]
+			}
+		}
+	}
+	formatted, err := format.Source([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimPrefix(string(formatted), "package p\n"), true
+}
+
+// Returns the action formatted with gofmt, or as it is if it is not a Go block of a single line.
+func (f *formatter) formatAction(action string) string {
+	formatted, ok := f.formatGo("func _() " + action)
+	if !ok {
+		return action
+	}
+	formatted = strings.TrimPrefix(strings.Trim(formatted, "\n"), "func _() ")
+	if strings.Contains(formatted, "\n") {
+		return action // Rules are read line by line
+	}
+	return formatted
+}
+
+func (f *formatter) namedPatterns(body []string) {
+	rows := make([]alignedRow, 0, len(body))
+	for _, line := range trimBlankLines(body) {
+		code, comment, _ := cutComment(line)
+		name, pattern, err := parseNamedPatternLine(code)
+		switch {
+		case strings.TrimSpace(line) == "":
+			rows = append(rows, alignedRow{})
+		case strings.TrimSpace(code) == "":
+			rows = append(rows, alignedRow{text: namedPatternIndent + strings.TrimSpace(line)})
+		case err != nil:
+			rows = append(rows, alignedRow{text: namedPatternIndent + strings.TrimSpace(line)})
+		default:
+			rows = append(rows, alignedRow{columns: [2]string{name, pattern}, comment: trailingComment(comment)})
+		}
+	}
+	f.lines = append(f.lines, "{")
+	f.lines = append(f.lines, alignRows(rows, namedPatternIndent)...)
+	f.lines = append(f.lines, "}")
+}
+
+func (f *formatter) rules(body []string) {
+	rows := make([]alignedRow, 0, len(body))
+	for _, line := range trimBlankLines(body) {
+		code, comment, _ := cutComment(line)
+		pattern, action, isRule := cutRule(strings.TrimSpace(code))
+		switch {
+		case strings.TrimSpace(line) == "":
+			rows = append(rows, alignedRow{})
+		case !isRule:
+			// Comments, or lines the parser rejects
+			rows = append(rows, alignedRow{text: strings.TrimSpace(line)})
+		default:
+			action = f.formatAction(strings.TrimSpace(action))
+			rows = append(rows, alignedRow{columns: [2]string{pattern, action}, comment: trailingComment(comment)})
+		}
+	}
+	f.lines = append(f.lines, "%%")
+	f.lines = append(f.lines, alignRows(rows, "")...)
+	f.lines = append(f.lines, "%%")
+}
+
+// A line of a section written in 2 columns, and a comment at its end. Lines that are
+// not in columns only have text, and blank lines have nothing.
+type alignedRow struct {
+	columns [2]string
+	comment string
+	text    string
+}
+
+func (r alignedRow) isBlank() bool {
+	return r.columns[0] == "" && r.text == ""
+}
+
+// Returns the rows with their second column and their comment starting at the same
+// column in each group of rows separated by blank lines.
+func alignRows(rows []alignedRow, indent string) []string {
+	lines := make([]string, 0, len(rows))
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && !rows[end].isBlank() {
+			end++
+		}
+
+		widths := [2]int{}
+		for _, row := range rows[start:end] {
+			if row.text == "" {
+				widths[0] = max(widths[0], runeCount(row.columns[0]))
+				widths[1] = max(widths[1], runeCount(row.columns[1]))
+			}
+		}
+		for _, row := range rows[start:end] {
+			if row.text != "" {
+				lines = append(lines, row.text)
+				continue
+			}
+			line := indent + pad(row.columns[0], widths[0]) + "  " + row.columns[1]
+			if row.comment != "" {
+				line = indent + pad(row.columns[0], widths[0]) + "  " + pad(row.columns[1], widths[1]) + "  " + row.comment
+			}
+			lines = append(lines, line)
+		}
+
+		if end < len(rows) {
+			lines = append(lines, "")
+		}
+		// Runs of blank lines are written as one
+		for start = end; start < len(rows) && rows[start].isBlank(); start++ {
+		}
+	}
+	return lines
+}
+
+// Returns the text after "//" at the end of a line as a comment, empty if there is none.
+func trailingComment(text string) string {
+	if text = strings.TrimSpace(text); text == "" {
+		return ""
+	}
+	return "// " + text
+}
+
+// Removes the blank lines at the start and the end.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Removes the indentation all the lines start with.
+func dedent(lines []string) []string {
+	indent, first := "", true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lineIndent, false
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = strings.TrimPrefix(line, indent)
+	}
+	return result
+}
+
+func runeCount(text string) int {
+	return len([]rune(text))
+}
+
+// Returns text with spaces at its end up to width runes.
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(0, width-runeCount(text)))
+}
@@ -0,0 +1,193 @@
+package yalex_reader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func formatString(t *testing.T, definition string, options FormatOptions) string {
+	t.Helper()
+	var formatted bytes.Buffer
+	if err := Format(strings.NewReader(definition), &formatted, options); err != nil {
+		t.Fatal(err)
+	}
+	return formatted.String()
+}
+
+// Returns text without spaces, to compare code formatted with gofmt. Comment markers
+// are removed too, since gofmt may break doc comments in more lines.
+func withoutSpaces(text string) string {
+	text = strings.ReplaceAll(text, "//", "")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// Formatted definitions define the same lexer, and are already formatted.
+func TestFormatKeepsTheDefinition(t *testing.T) {
+	paths, err := filepath.Glob("../../../examples/*.lex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted := formatString(t, string(data), FormatOptions{GoCode: true})
+		if again := formatString(t, formatted, FormatOptions{GoCode: true}); again != formatted {
+			t.Errorf("%s: formatting twice changed it\n%s", path, again)
+		}
+
+		original, err := ParseReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := ParseReader(strings.NewReader(formatted))
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", path, err, formatted)
+		}
+		if withoutSpaces(original.Header) != withoutSpaces(result.Header) || withoutSpaces(original.Footer) != withoutSpaces(result.Footer) {
+			t.Errorf("%s: the header or the footer changed\n%s", path, formatted)
+		}
+		if len(original.Rules) != len(result.Rules) {
+			t.Fatalf("%s: expected %d rules, got %d\n%s", path, len(original.Rules), len(result.Rules), formatted)
+		}
+		for i, rule := range original.Rules {
+			if rule.Pattern != result.Rules[i].Pattern || withoutSpaces(rule.Action) != withoutSpaces(result.Rules[i].Action) {
+				t.Errorf("%s: expected rule %+v, got %+v", path, rule, result.Rules[i])
+			}
+		}
+		if len(original.NamedPatterns.patterns) != len(result.NamedPatterns.patterns) {
+			t.Errorf("%s: expected %d named patterns, got %d", path, len(original.NamedPatterns.patterns), len(result.NamedPatterns.patterns))
+		}
+		for name, pattern := range original.NamedPatterns.patterns {
+			if other, exist := result.NamedPatterns.Lookup(name); !exist || other.Pattern != pattern.Pattern {
+				t.Errorf("%s: expected {%s} to be %s, got %v", path, name, pattern.Pattern, other)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	definition := `%option   case-insensitive
+
+
+%{
+    import "strings"
+      const ( A = iota
+      B )
+%}
+{
+    // Letters
+    let letter = [a-z]   // Only ASCII
+    let identifier = {letter}+
+
+    digit [0-9]
+}
+%%
+
+{identifier}  {return A}  //Identifiers
+"if"  {  strings.ToUpper( yytext ); return B }
+// Numbers
+{digit}+  {}
+%%
+%{
+  func f() {  }
+%}
+`
+	expected := `%option case-insensitive
+
+%{
+import "strings"
+
+const (
+	A = iota
+	B
+)
+%}
+{
+    // Letters
+    letter      [a-z]      // Only ASCII
+    identifier  {letter}+
+
+    digit  [0-9]
+}
+%%
+{identifier}  { return A }                           // Identifiers
+"if"          { strings.ToUpper(yytext); return B }
+// Numbers
+{digit}+      {}
+%%
+%{
+func f() {}
+%}
+`
+	if formatted := formatString(t, definition, FormatOptions{GoCode: true}); formatted != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, formatted)
+	}
+
+	// Code of other languages is only dedented
+	c := "%{\n    #include <stdio.h>\n      int x;\n%}\n%%\n[0-9]+  {   return 1; }\n%%\n"
+	expectedC := "%{\n#include <stdio.h>\n  int x;\n%}\n%%\n[0-9]+  {   return 1; }\n%%\n"
+	if formatted := formatString(t, c, FormatOptions{}); formatted != expectedC {
+		t.Errorf("expected\n%s\ngot\n%s", expectedC, formatted)
+	}
+
+	if err := Format(strings.NewReader("%{\nint x;\n%%\n"), &bytes.Buffer{}, FormatOptions{}); err == nil {
+		t.Errorf("expected an error for a header without %%}")
+	}
+}
+
+// Comments and double spaces inside quotes and classes are part of the patterns.
+func TestFormatKeepsQuotedPatterns(t *testing.T) {
+	definition := `{
+    COMMENT "//"[^\n]*  // Until the end of the line
+}
+%%
+"//"[^\n]*  {return C}  // Comments
+"a  b"  { return AB }
+[/ ]"  "  { return SPACES }
+%%
+`
+	expected := `{
+    COMMENT  "//"[^\n]*  // Until the end of the line
+}
+%%
+"//"[^\n]*  { return C }       // Comments
+"a  b"      { return AB }
+[/ ]"  "    { return SPACES }
+%%
+`
+	formatted := formatString(t, definition, FormatOptions{GoCode: true})
+	if formatted != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, formatted)
+	}
+	if again := formatString(t, formatted, FormatOptions{GoCode: true}); again != formatted {
+		t.Errorf("formatting twice changed it\n%s", again)
+	}
+
+	original, err := ParseReader(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseReader(strings.NewReader(formatted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rule := range original.Rules {
+		if i >= len(result.Rules) || rule.Pattern != result.Rules[i].Pattern {
+			t.Errorf("expected rule %+v, got %+v", rule, result.Rules)
+		}
+	}
+	if pattern, _ := result.NamedPatterns.Lookup("COMMENT"); pattern == nil || pattern.Pattern != `"//"[^\n]*` {
+		t.Errorf("expected COMMENT to be %q, got %v", `"//"[^\n]*`, pattern)
+	}
+}
@@ -12,12 +12,25 @@ PIPELINE
 */
 
 type YALexDefinition struct {
-	Header string
-	Footer string
-	Rules  []YALexRule
+	Header        string
+	Footer        string
+	Options       YALexOptions
+	NamedPatterns *Scope
+	Rules         []YALexRule
+}
+
+// Options set with "%option name" lines outside the other sections.
+type YALexOptions struct {
+	CaseInsensitive bool // "%option case-insensitive", all rules ignore the case of letters
 }
 
 type YALexRule struct {
 	Pattern string
 	Action  string
 }
+
+// A pattern of the named patterns section. Ex: "id {letter}({letter}|{digit})*"
+type NamedPattern struct {
+	Name    string
+	Pattern string
+}
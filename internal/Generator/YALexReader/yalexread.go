@@ -1,14 +1,27 @@
 package yalex_reader
 
 import (
+	"fmt"
+	goio "io"
 	"strings"
 
 	io "github.com/DanielRasho/Lexer/internal/IO"
 )
 
 func Parse(filePath string) (*YALexDefinition, error) {
+	filereader, err := io.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parse(filereader)
+}
+
+// Igual que Parse, leyendo la definición de source.
+func ParseReader(source goio.Reader) (*YALexDefinition, error) {
+	return parse(io.NewReader(source))
+}
 
-	filereader, _ := io.ReadFile(filePath)
+func parse(filereader *io.FileReader) (*YALexDefinition, error) {
 	var line string
 
 	//Esto es utilizado para leer el cuerpo ya sea del header, footer, reglas
@@ -24,10 +37,20 @@ func Parse(filePath string) (*YALexDefinition, error) {
 	var Footer string
 	Rules := make([]string, 0)
 	Tokens := make([]string, 0)
-	hashtokens := make(map[string]string, 0)
 	YalRules := make([]YALexRule, 0)
 
+	var Options YALexOptions
+
 	for filereader.NextLine(&line) {
+		// Options, only outside the other sections
+		if strings.HasPrefix(line, "%option") && !readinghead && !readingbody && !readingrules && !readingfooter {
+			if err := parseOptions(line, &Options); err != nil {
+				filereader.Close()
+				return nil, err
+			}
+			continue
+		}
+
 		// Starting with header once it finds the end -->
 		if line == "%{\n" || readinghead {
 
@@ -107,60 +130,59 @@ func Parse(filePath string) (*YALexDefinition, error) {
 	}
 
 	//Quitas las llaves
-	Tokens = Tokens[1 : len(Tokens)-1]
+	NamedPatterns := NewScope()
+	if len(Tokens) >= 2 {
+		Tokens = Tokens[1 : len(Tokens)-1]
+	}
 	for i := range len(Tokens) {
-		//Espacios en blancos lineas en blanco
-		if strings.TrimSpace(Tokens[i]) != "" {
-
-			Tokens[i] = strings.Split(Tokens[i], "//")[0]
-			//Encuentra un comentario
-			if strings.TrimSpace(Tokens[i]) != "" {
-
-				//Esta seccion guarda la expresion regexp y la accion que se debe de tomar,
-				Tokens[i] = strings.TrimSpace(Tokens[i])
-				key := strings.SplitAfterN(Tokens[i], " ", 2)[0]
-				key = strings.TrimSpace(key)
-				key = "{" + key + "}"
-				value := strings.SplitAfterN(Tokens[i], " ", 2)[1]
-				value = strings.TrimSpace(value)
-				value = strings.ReplaceAll(value, `\t`, "\t") // Replace tab with space
-				value = strings.ReplaceAll(value, `\n`, "\n") // Replace newline with space
-				value = strings.ReplaceAll(value, `\r`, "\r") // Replace carriage return with space
-				for i, valor := range hashtokens {
-					value = strings.ReplaceAll(value, i, valor)
-				}
-
-				hashtokens[key] = value
-			}
-
+		//Quita los comentarios y las lineas en blanco
+		Tokens[i], _, _ = cutComment(Tokens[i])
+		if strings.TrimSpace(Tokens[i]) == "" {
+			continue
 		}
 
+		//Guarda el nombre y su patron, las referencias se resuelven despues por nombre
+		name, pattern, err := parseNamedPatternLine(Tokens[i])
+		if err == nil {
+			err = NamedPatterns.Define(name, pattern)
+		}
+		if err != nil {
+			filereader.Close()
+			return nil, err
+		}
+	}
+	if err := NamedPatterns.Validate(); err != nil {
+		filereader.Close()
+		return nil, err
 	}
 
 	//Solo quita las llaves {  }
-	Rules = Rules[1 : len(Rules)-1]
+	if len(Rules) == 0 {
+		filereader.Close()
+		return nil, fmt.Errorf("missing rules section, start it with a \"%%%%\" line")
+	}
+	Rules = Rules[1:]
+	if len(Rules) > 0 && Rules[len(Rules)-1] == "%%\n" {
+		Rules = Rules[:len(Rules)-1]
+	}
 	for i := range len(Rules) {
 		if strings.TrimSpace(Rules[i]) != "" {
 			yal := YALexRule{Pattern: "", Action: ""}
-			Rules[i] = strings.Split(Rules[i], "//")[0]
+			Rules[i], _, _ = cutComment(Rules[i])
 			Rules[i] = strings.TrimSpace(Rules[i])
+			if Rules[i] == "" {
+				continue // Solo un comentario
+			}
 			//Esta seccion guarda la expresion regexp y la accion que se debe de tomar,
-			key_change := strings.TrimSpace(strings.SplitAfterN(Rules[i], "  ", 2)[0])
-
-			pattern, exist := hashtokens[key_change]
-
-			if !exist {
-				pattern = key_change
-				if strings.Compare(pattern, "\"\"\"") != 0 {
-					pattern = strings.ReplaceAll(pattern, "\"", "")
-				} else {
-					pattern = pattern[1:]
-					pattern = pattern[:len(pattern)-1]
-				}
+			pattern, action, found := cutRule(Rules[i])
+			if !found {
+				filereader.Close()
+				return nil, fmt.Errorf("rule %q has no action, separate it from the pattern with two spaces", Rules[i])
 			}
 
-			yal.Pattern = pattern
-			yal.Action = strings.TrimSpace(strings.SplitAfterN(Rules[i], "  ", 2)[1])
+			// Quotes, escape sequences and named patterns are resolved later by the regex parser
+			yal.Pattern = strings.TrimSpace(pattern)
+			yal.Action = strings.TrimSpace(action)
 
 			YalRules = append(YalRules, yal)
 		}
@@ -170,11 +192,68 @@ func Parse(filePath string) (*YALexDefinition, error) {
 	filereader.Close()
 
 	yalexdef := YALexDefinition{
-		Header: Header,
-		Footer: Footer,
-		Rules:  YalRules,
+		Header:        Header,
+		Footer:        Footer,
+		Options:       Options,
+		NamedPatterns: NamedPatterns,
+		Rules:         YalRules,
 	}
 
 	return &yalexdef, nil
 
 }
+
+// Sets the options of a line like "%option case-insensitive".
+func parseOptions(line string, options *YALexOptions) error {
+	for _, option := range strings.Fields(strings.TrimPrefix(line, "%option")) {
+		switch option {
+		case "case-insensitive", "caseless":
+			options.CaseInsensitive = true
+		case "case-sensitive":
+			options.CaseInsensitive = false
+		default:
+			return fmt.Errorf("unknown option %q", option)
+		}
+	}
+	return nil
+}
+
+// Splits a line at the first "//" outside of quotes, into its code and its comment.
+func cutComment(line string) (code, comment string, found bool) {
+	if i := indexUnquoted(line, "//"); i >= 0 {
+		return line[:i], line[i+len("//"):], true
+	}
+	return line, "", false
+}
+
+// Splits a rule at the first two spaces outside of quotes, into its pattern and its action.
+func cutRule(rule string) (pattern, action string, found bool) {
+	if i := indexUnquoted(rule, "  "); i >= 0 {
+		return rule[:i], rule[i+len("  "):], true
+	}
+	return rule, "", false
+}
+
+// Returns the index of the first sep in line that is not inside a quoted string ("..." or
+// '...') or a character class ([...]), -1 if there is none. A backslash escapes the byte
+// after it, so "\"" doesn't end a string.
+func indexUnquoted(line, sep string) int {
+	var closing byte // Byte that ends the string or class being read, 0 outside of them
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case closing != 0:
+			if c == closing {
+				closing = 0
+			}
+		case c == '"' || c == '\'':
+			closing = c
+		case c == '[':
+			closing = ']'
+		case strings.HasPrefix(line[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
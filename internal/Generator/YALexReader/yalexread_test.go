@@ -4,6 +4,8 @@ package yalex_reader
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -26,3 +28,40 @@ func Test_check_DFA(t *testing.T) {
 	}
 
 }
+
+// Comments and the two spaces before the action are only found outside of quotes and classes.
+func TestParseQuotedPatterns(t *testing.T) {
+	definition := `{
+    COMMENT  "//"[^\n]*  // Until the end of the line
+    SLASHES  ['/' '/']
+}
+%%
+"//"[^\n]*  { return C }  // Comments
+"a  b"  { return AB }
+'"'[^"]*'"'  { return S }
+[/ ]"  "  { return SPACES }
+"\"//"  { return Q }
+{COMMENT}  { return C }
+%%
+`
+	definitionRead, err := ParseReader(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []YALexRule{
+		{Pattern: `"//"[^\n]*`, Action: "{ return C }"},
+		{Pattern: `"a  b"`, Action: "{ return AB }"},
+		{Pattern: `'"'[^"]*'"'`, Action: "{ return S }"},
+		{Pattern: `[/ ]"  "`, Action: "{ return SPACES }"},
+		{Pattern: `"\"//"`, Action: "{ return Q }"},
+		{Pattern: `{COMMENT}`, Action: "{ return C }"},
+	}
+	if !slices.Equal(definitionRead.Rules, expected) {
+		t.Errorf("expected %q\ngot %q", expected, definitionRead.Rules)
+	}
+	for name, pattern := range map[string]string{"COMMENT": `"//"[^\n]*`, "SLASHES": `['/' '/']`} {
+		if got := definitionRead.NamedPatterns.patterns[name].Pattern; got != pattern {
+			t.Errorf("expected %s to be %q, got %q", name, pattern, got)
+		}
+	}
+}
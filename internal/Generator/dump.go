@@ -0,0 +1,162 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// This file writes the intermediate artifacts of the generation as JSON, for tooling.
+// Each one is written to its own file: postfix.json, positions.json and states.json.
+
+// Intermediate artifact of the generation of a lexer.
+type DumpArtifact string
+
+const (
+	DUMP_POSTFIX   DumpArtifact = "postfix"   // Regex of all rules in postfix notation
+	DUMP_POSITIONS DumpArtifact = "positions" // Position table of the direct construction
+	DUMP_STATES    DumpArtifact = "states"    // States of the DFA the lexer is generated with
+)
+
+// Returns the artifacts of a comma separated list. Ex: "postfix,states"
+func ParseDumpArtifacts(list string) ([]DumpArtifact, error) {
+	artifacts := make([]DumpArtifact, 0)
+	for _, name := range strings.Split(list, ",") {
+		switch artifact := DumpArtifact(strings.TrimSpace(name)); artifact {
+		case DUMP_POSTFIX, DUMP_POSITIONS, DUMP_STATES:
+			artifacts = append(artifacts, artifact)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown artifact %q to dump, use %q, %q or %q", name, DUMP_POSTFIX, DUMP_POSITIONS, DUMP_STATES)
+		}
+	}
+	return artifacts, nil
+}
+
+type postfixDump struct {
+	Expression string       `json:"expression"`
+	Symbols    []symbolDump `json:"symbols"`
+}
+
+type symbolDump struct {
+	Value    string      `json:"value"`
+	Operator bool        `json:"operator,omitempty"`
+	Operands int         `json:"operands,omitempty"`
+	Ranges   []rangeDump `json:"ranges,omitempty"` // Runes matched by a character or class
+	Rule     *int        `json:"rule,omitempty"`   // Rule (by priority) of an action symbol
+	Anchor   string      `json:"anchor,omitempty"` // "line_start" or "trailing_context"
+}
+
+type rangeDump struct {
+	From rune `json:"from"`
+	To   rune `json:"to"`
+}
+
+type statesDump struct {
+	Start     string      `json:"start"`
+	LineStart string      `json:"lineStart"`
+	States    []stateDump `json:"states"`
+	Rules     []ruleDump  `json:"rules"`
+}
+
+type stateDump struct {
+	Id          string           `json:"id"`
+	Rule        int              `json:"rule"` // -1 if the state is not final
+	HeadEnds    []int            `json:"headEnds,omitempty"`
+	Transitions []transitionDump `json:"transitions"`
+}
+
+type transitionDump struct {
+	From  rune   `json:"from"`
+	To    rune   `json:"to"`
+	State string `json:"state"`
+}
+
+type ruleDump struct {
+	Priority        int    `json:"priority"`
+	Action          string `json:"action"`
+	TrailingContext bool   `json:"trailingContext,omitempty"`
+}
+
+// Writes the artifact to dir. The states are the ones of automata, the others are built from expresion.
+func dumpArtifact(artifact DumpArtifact, dir string, expresion postfix.Regex, automata *dfa.DFA) error {
+	var content any
+	switch artifact {
+	case DUMP_POSTFIX:
+		symbols, err := postfix.ToPostfix(expresion)
+		if err != nil {
+			return err
+		}
+		content = newPostfixDump(symbols)
+	case DUMP_POSITIONS:
+		rows, err := dfa.PositionTable(expresion)
+		if err != nil {
+			return err
+		}
+		content = rows
+	case DUMP_STATES:
+		content = newStatesDump(automata)
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, string(artifact)+".json"), append(data, '\n'), 0o644)
+}
+
+func newPostfixDump(symbols []postfix.Symbol) postfixDump {
+	dump := postfixDump{Expression: dfa.PostfixString(symbols), Symbols: make([]symbolDump, len(symbols))}
+	for i, symbol := range symbols {
+		s := symbolDump{Value: symbol.Value, Operator: symbol.IsOperator}
+		if symbol.IsOperator {
+			s.Operands = symbol.Operands
+		}
+		for _, r := range symbol.Ranges {
+			s.Ranges = append(s.Ranges, rangeDump{From: r.From, To: r.To})
+		}
+		if symbol.Action.Priority >= 0 {
+			s.Rule = &symbol.Action.Priority
+		}
+		switch symbol.Anchor {
+		case postfix.LINE_START:
+			s.Anchor = "line_start"
+		case postfix.TRAILING_CONTEXT:
+			s.Anchor = "trailing_context"
+		}
+		dump.Symbols[i] = s
+	}
+	return dump
+}
+
+func newStatesDump(automata *dfa.DFA) statesDump {
+	dump := statesDump{Start: automata.StartState.Id, LineStart: automata.StartState.Id}
+	if automata.LineStartState != nil {
+		dump.LineStart = automata.LineStartState.Id
+	}
+	for _, state := range automata.States {
+		s := stateDump{Id: state.Id, Rule: -1, HeadEnds: state.HeadEnds, Transitions: make([]transitionDump, 0)}
+		if state.IsFinal {
+			s.Rule = state.Rule
+		}
+		for _, symbol := range dfa.SortedSymbols(state.Transitions) {
+			r := []rune(symbol)[0]
+			s.Transitions = append(s.Transitions, transitionDump{From: r, To: r, State: state.Transitions[symbol].Id})
+		}
+		for _, r := range state.Ranges {
+			s.Transitions = append(s.Transitions, transitionDump{From: r.From, To: r.To, State: r.State.Id})
+		}
+		sort.Slice(s.Transitions, func(i, j int) bool { return s.Transitions[i].From < s.Transitions[j].From })
+		dump.States = append(dump.States, s)
+	}
+	for _, rule := range automata.Rules {
+		dump.Rules = append(dump.Rules, ruleDump{Priority: rule.Priority, Action: rule.Code, TrailingContext: rule.TrailingContext})
+	}
+	return dump
+}
@@ -1,27 +1,188 @@
 package generator
 
 import (
+	"bytes"
 	"fmt"
+	"go/token"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	dfa "github.com/DanielRasho/Lexer/internal/DFA"
-	balancer "github.com/DanielRasho/Lexer/internal/DFA/Balancer"
 	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
 	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+	report "github.com/DanielRasho/Lexer/internal/Generator/Report"
 	yalex_reader "github.com/DanielRasho/Lexer/internal/Generator/YALexReader"
 )
 
+// How the generated lexer handles bytes that are not valid UTF-8.
+type InvalidUTF8Policy string
+
+const (
+	INVALID_UTF8_ERROR   InvalidUTF8Policy = "error"   // Returned as INVALID_UTF8 tokens
+	INVALID_UTF8_REPLACE InvalidUTF8Policy = "replace" // Read as U+FFFD, the replacement character
+)
+
+// Returns the policy with the given name. Ex: "error", "replace"
+func ParseInvalidUTF8Policy(name string) (InvalidUTF8Policy, error) {
+	switch policy := InvalidUTF8Policy(name); policy {
+	case INVALID_UTF8_ERROR, INVALID_UTF8_REPLACE:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown invalid UTF-8 policy %q, use %q or %q", name, INVALID_UTF8_ERROR, INVALID_UTF8_REPLACE)
+}
+
+// Options of the generation of a lexer. The zero value uses the default of each one.
+type Options struct {
+	Construction dfa.Construction  // Algorithm used to build the DFA, direct by default
+	Target       Lex_writer.Target // Language the lexer is written in, Go by default
+	Package      string            // Package of Go lexers, main by default
+	InvalidUTF8  InvalidUTF8Policy // How the lexer handles invalid input, error by default
+	Logger       *slog.Logger      // Receives the time each phase takes, and its details at debug level. Nil to log nothing
+	EmitAST      string            // Path to write the diagram of the syntax tree to, none if empty
+	EmitDFA      string            // Path to write the diagram of the DFA to, none if empty
+	Report       string            // Path to write the HTML report of the lexer to, none if empty
+	Automaton    string            // Path to write the DFA to, as JSON or binary (see dfa.WriteAutomaton), none if empty
+	Dump         []DumpArtifact    // Intermediate artifacts to write as JSON
+	DumpDir      string            // Directory to write the artifacts to, the one of the output by default
+	SpecPath     string            // Path of the definition written in the stamp of the lexer, relative to it. None if empty
+}
+
+// Lexer of a definition, built in memory before it is written.
+type Lexer struct {
+	Definition *yalex_reader.YALexDefinition
+	Rules      []report.Rule // Pattern, action and regex of each rule, by priority
+	Expresion  postfix.Regex // Regex of all rules, each one ending in the symbol of its action
+	Automata   *dfa.DFA      // DFA without absorption states
+}
+
 // Given a file to read and a output path, writes a lexer definition to the desired path.
-func Compile(filePath, outputPath string, showLogs bool) error {
+// Diagrams are written in the format of the extension of their path (see dfa.DiagramFormatOf).
+//
+// The phases of the generation are logged as they end: parse, regex, ast, dfa, minimize and emit.
+func Compile(filePath, outputPath string, options Options) error {
+	if options.DumpDir == "" {
+		options.DumpDir = filepath.Dir(outputPath)
+	}
+	if options.SpecPath == "" {
+		options.SpecPath = RelativeSpecPath(filePath, outputPath)
+	}
+	source, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
 
-	// Parse Yalex file definition
-	yalexDefinition, err := yalex_reader.Parse(filePath)
+	// The output is only written if the whole generation succeeds
+	var output bytes.Buffer
+	if err := Generate(source, filePath, &output, options); err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, output.Bytes(), 0o644)
+}
+
+// Same as Compile, reading the definition from source and writing the lexer to output.
+// sourceName is the name of the definition in logs and reports.
+// Intermediate artifacts are written to the working directory if options.DumpDir is empty.
+func Generate(source io.Reader, sourceName string, output io.Writer, options Options) error {
+	options = options.withDefaults()
+	if !token.IsIdentifier(options.Package) {
+		return fmt.Errorf("invalid package name %q", options.Package)
+	}
+	// Fail before doing any work if a diagram can't be written
+	for _, diagram := range []string{options.EmitAST, options.EmitDFA} {
+		if _, err := dfa.DiagramFormatOf(diagram); diagram != "" && err != nil {
+			return err
+		}
+	}
+
+	// The definition is hashed for the stamp of the lexer
+	spec, err := io.ReadAll(source)
+	if err != nil {
+		return err
+	}
+	lexer, err := Build(bytes.NewReader(spec), sourceName, options)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	for _, artifact := range options.Dump {
+		if err := dumpArtifact(artifact, options.DumpDir, lexer.Expresion, lexer.Automata); err != nil {
+			return fmt.Errorf("dumping %s: %w", artifact, err)
+		}
+	}
+	if options.EmitAST != "" {
+		if err := dfa.WriteASTDiagram(lexer.Expresion, options.EmitAST); err != nil {
+			return fmt.Errorf("writing syntax tree diagram: %w", err)
+		}
+	}
+	if options.EmitDFA != "" {
+		if err := dfa.WriteDFADiagram(lexer.Automata, options.EmitDFA); err != nil {
+			return fmt.Errorf("writing DFA diagram: %w", err)
+		}
+	}
+	if options.Automaton != "" {
+		if err := dfa.WriteAutomaton(lexer.Automata, options.Automaton); err != nil {
+			return fmt.Errorf("writing automaton: %w", err)
+		}
+	}
+	if options.Report != "" {
+		if err := report.Write(options.Report, filepath.Base(sourceName), lexer.Rules, lexer.Expresion, lexer.Automata); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	}
+
+	stamp := Stamp{
+		Version:      VERSION,
+		Hash:         SpecHash(spec),
+		Target:       options.Target,
+		Construction: options.Construction,
+		InvalidUTF8:  options.InvalidUTF8,
+		Spec:         options.SpecPath,
+	}
+	if options.Target == Lex_writer.GO_TARGET {
+		stamp.Package = options.Package
+	}
+	if _, err := io.WriteString(output, stamp.String()); err != nil {
+		return err
+	}
+	backend := Lex_writer.NewBackend(options.Target)
+	err = backend.Write(Lex_writer.LexSpec{
+		Definition:         lexer.Definition,
+		Automata:           lexer.Automata,
+		ReplaceInvalidUTF8: options.InvalidUTF8 == INVALID_UTF8_REPLACE,
+		Package:            options.Package,
+	}, output)
 	if err != nil {
 		return err
 	}
+	options.Logger.Info("phase", "phase", "emit", "duration", time.Since(start), "target", options.Target)
+
+	return nil
+}
+
+// Builds the lexer of the definition read from source, without writing anything.
+// Only options.Construction and options.Logger are used.
+func Build(source io.Reader, sourceName string, options Options) (*Lexer, error) {
+	options = options.withDefaults()
+	logger := options.Logger
+
+	// Parse Yalex file definition
+	start := time.Now()
+	yalexDefinition, err := yalex_reader.ParseReader(source)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("phase", "phase", "parse", "duration", time.Since(start), "file", sourceName, "rules", len(yalexDefinition.Rules))
+	start = time.Now()
 
 	// Join all rules in a single regex expression alongside its special symbol
-	rawExpresion := make([]postfix.RawSymbol, 0)
+	rules := make([]postfix.Regex, 0, len(yalexDefinition.Rules))
+	reportRules := make([]report.Rule, 0, len(yalexDefinition.Rules))
 
 	for index, rule := range yalexDefinition.Rules {
 		// For special tokens (the ones encapsulating actionable code)
@@ -32,51 +193,80 @@ func Compile(filePath, outputPath string, showLogs bool) error {
 		// Therefore a easy technique is to assign them an id starting in 10.
 		startIndex := 10
 
-		ok, _ := balancer.IsBalanced(rule.Pattern)
-		if !ok {
-			return fmt.Errorf("rule %s, has an unbalanced pattern", rule.Pattern)
+		// Anchors and trailing context are represented by special symbols
+		// that mark a position within the rule: ^pattern/(trailing context)
+		rulePattern, err := postfix.ParseRule(rule.Pattern, postfix.ParseOptions{
+			CaseInsensitive: yalexDefinition.Options.CaseInsensitive,
+			Resolve:         yalexDefinition.NamedPatterns.Resolve,
+		})
+		if syntaxErr, isSyntaxErr := err.(*postfix.SyntaxError); isSyntaxErr {
+			syntaxErr.Rule = index + 1
+			return nil, fmt.Errorf("%w: %s", err, rule.Pattern)
+		} else if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Pattern, err)
 		}
-
-		rawExpresion = append(rawExpresion, postfix.RawSymbol{Value: "("})
-		for _, r := range rule.Pattern {
-			rawExpresion = append(rawExpresion, postfix.RawSymbol{
-				Value:  string(r),
-				Action: postfix.Action{Priority: -1}})
+		if _, fixed := rulePattern.TrailingLength(); rulePattern.TrailingContext != nil && !fixed {
+			logger.Warn("trailing context of variable length, the lexeme may include part of it where it overlaps the pattern before \"/\"", "rule", index+1, "pattern", rule.Pattern)
 		}
-		rawExpresion = append(rawExpresion, postfix.RawSymbol{Value: ")"})
-		rawExpresion = append(rawExpresion, postfix.RawSymbol{
+
+		rules = append(rules, rulePattern.Regex(postfix.Accept{
 			Value: strconv.Itoa(index + startIndex),
 			Action: postfix.Action{
 				Priority: index,
-				Code:     rule.Action}})
-
-		if index != len(yalexDefinition.Rules)-1 {
-			rawExpresion = append(rawExpresion, postfix.RawSymbol{Value: "|"})
-		}
-
-	}
-
-	if showLogs {
-		for _, v := range rawExpresion {
-			fmt.Print(v.Value)
-		}
-		fmt.Println("")
+				Code:     rule.Action}}))
+		reportRules = append(reportRules, report.Rule{Pattern: rule.Pattern, Action: rule.Action, Regex: rules[index]})
 	}
+	expresion := postfix.Alt{Options: rules}
+	logger.Info("phase", "phase", "regex", "duration", time.Since(start))
+	logger.Debug("regex", "expression", expresion.String())
 
 	// Generate DFA for language recognition
-	automata, numFinalSymbols, err := dfa.NewDFA(rawExpresion, showLogs)
+	automata, numFinalSymbols, err := dfa.NewDFA(expresion, options.Construction, logger)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	dfa.PrintDFA(automata)
-
 	//Despues de minimize
+	start = time.Now()
+	numStates := len(automata.States)
 	dfa.RemoveAbsortionStates(automata, numFinalSymbols) //Destructive operation
-	dfa.RenderDFA(automata, "./diagram/automataFinal.png")
+	logger.Info("phase", "phase", "minimize", "duration", time.Since(start), "removed", numStates-len(automata.States), "states", len(automata.States))
 
-	lextemp := Lex_writer.CreateLexTemplateComponentes(yalexDefinition, automata)
-	Lex_writer.FillwithTemplate("./template/LexTemplate.go", lextemp, outputPath)
+	return &Lexer{Definition: yalexDefinition, Rules: reportRules, Expresion: expresion, Automata: automata}, nil
+}
 
-	return nil
+// Returns the path of the definition at specPath relative to the directory of the lexer at
+// outputPath, as written in its stamp. The absolute path of specPath if there is no relative path.
+func RelativeSpecPath(specPath, outputPath string) string {
+	absSpec, err := filepath.Abs(specPath)
+	if err != nil {
+		return specPath
+	}
+	absOutput, err := filepath.Abs(outputPath)
+	if err != nil {
+		return absSpec
+	}
+	if relative, err := filepath.Rel(filepath.Dir(absOutput), absSpec); err == nil {
+		return relative
+	}
+	return absSpec
+}
+
+func (options Options) withDefaults() Options {
+	if options.Construction == "" {
+		options.Construction = dfa.DIRECT_CONSTRUCTION
+	}
+	if options.InvalidUTF8 == "" {
+		options.InvalidUTF8 = INVALID_UTF8_ERROR
+	}
+	if options.Target == "" {
+		options.Target = Lex_writer.GO_TARGET
+	}
+	if options.Package == "" {
+		options.Package = "main"
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return options
 }
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+)
+
+// Example of the repository, from the directory of the tests.
+var exampleSpec = filepath.Join("..", "..", "examples", "example5.lex")
+
+// Generated lexers are checked in, so generating twice must produce the same file.
+func TestCompileIsDeterministic(t *testing.T) {
+	for _, construction := range []dfa.Construction{dfa.DIRECT_CONSTRUCTION, dfa.THOMPSON_CONSTRUCTION} {
+		outputs := make([][]byte, 2)
+		for i := range outputs {
+			outputPath := filepath.Join(t.TempDir(), "lexer.go")
+			if err := Compile(exampleSpec, outputPath, Options{Construction: construction}); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			if outputs[i], err = os.ReadFile(outputPath); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(outputs[0], outputs[1]) {
+			t.Errorf("%s construction generated different lexers for the same spec", construction)
+		}
+	}
+}
+
+func TestCompileLogsPhasesAndDumpsArtifacts(t *testing.T) {
+	var logs bytes.Buffer
+	dir := t.TempDir()
+	err := Compile(exampleSpec, filepath.Join(dir, "lexer.go"), Options{
+		Logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+		Dump:   []DumpArtifact{DUMP_POSTFIX, DUMP_POSITIONS, DUMP_STATES},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	phases := make([]string, 0)
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var record struct {
+			Msg   string
+			Phase string `json:"phase"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Msg == "phase" {
+			phases = append(phases, record.Phase)
+		}
+	}
+	if expected := []string{"parse", "regex", "ast", "dfa", "minimize", "emit"}; !slices.Equal(phases, expected) {
+		t.Errorf("logged phases %v, expected %v", phases, expected)
+	}
+
+	for _, artifact := range []DumpArtifact{DUMP_POSTFIX, DUMP_POSITIONS} {
+		data, err := os.ReadFile(filepath.Join(dir, string(artifact)+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(data) {
+			t.Errorf("%s.json is not valid JSON", artifact)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "states.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states statesDump
+	if err := json.Unmarshal(data, &states); err != nil {
+		t.Fatal(err)
+	}
+	if len(states.States) == 0 || states.Start == "" {
+		t.Errorf("states.json has no states: %+v", states)
+	}
+}
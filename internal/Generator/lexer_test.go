@@ -0,0 +1,355 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DanielRasho/Lexer/runtime"
+)
+
+// Prints every token the generated lexer finds in in.txt, one per line: "ID VALUE OFFSET".
+// Then, if the input has an invalid lexeme, its position: "error LINE COLUMN PATTERN"
+// The input is read in the encoding that replaces ENCODING.
+const lexerMain = `package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	file, err := os.Open("in.txt")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	lexer := NewLexerWithEncoding(file, ENCODING)
+	for {
+		token, err := lexer.GetNextToken()
+		if err == io.EOF {
+			return
+		} else if notFound, ok := err.(*PatternNotFound); ok {
+			fmt.Printf("error %d %d %q\n", notFound.Line, notFound.Column, notFound.Pattern)
+			return
+		} else if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d %q %d\n", token.TokenID, token.String(), token.Offset)
+	}
+}
+`
+
+// Generates a lexer with the given rules, runs it over input and returns the tokens it found.
+// Each rule is written in its own line, with its pattern and action separated by 2 spaces.
+func runLexer(t *testing.T, rules, input string, invalidUTF8 InvalidUTF8Policy) []string {
+	return runLexerWithEncoding(t, rules, input, invalidUTF8, "UTF8")
+}
+
+// Same as runLexer, for input in the given encoding of the generated lexer. Ex: "UTF16LE"
+func runLexerWithEncoding(t *testing.T, rules, input string, invalidUTF8 InvalidUTF8Policy, encoding string) []string {
+	dir := t.TempDir()
+	spec := "%{\n%}\n{\n}\n%%\n" + rules + "%%\n"
+	files := map[string]string{
+		"spec.lex": spec,
+		"in.txt":   input,
+		"main.go":  strings.Replace(lexerMain, "ENCODING", encoding, 1),
+		"go.mod":   "module lexer\n\ngo 1.23\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options := Options{InvalidUTF8: invalidUTF8, Automaton: filepath.Join(dir, "automaton.bin")}
+	if err := Compile(filepath.Join(dir, "spec.lex"), filepath.Join(dir, "lexer.go"), options); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	tokens := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+
+	// The runtime must find the same tokens with the automaton of the lexer
+	if runtimeTokens := runRuntime(t, options.Automaton, input, invalidUTF8, encoding); !slices.Equal(runtimeTokens, tokens) {
+		t.Errorf("the runtime found %v\nthe generated lexer %v", runtimeTokens, tokens)
+	}
+	return tokens
+}
+
+// Same as lexerMain, with the runtime package and the automaton at path.
+// Tokens are printed with the name of their rule, which is the token id the tests return.
+func runRuntime(t *testing.T, path, input string, invalidUTF8 InvalidUTF8Policy, encoding string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner, err := runtime.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodings := map[string]runtime.Encoding{"UTF8": runtime.UTF8, "UTF16LE": runtime.UTF16LE, "UTF16BE": runtime.UTF16BE, "LATIN1": runtime.LATIN1}
+	scanner.ReplaceInvalidUTF8 = invalidUTF8 == INVALID_UTF8_REPLACE
+	scanner.ResetWithEncoding(strings.NewReader(input), encodings[encoding])
+
+	tokens := make([]string, 0)
+	for {
+		token, err := scanner.GetNextToken()
+		if err == io.EOF {
+			break
+		} else if notFound, ok := err.(*runtime.PatternNotFound); ok {
+			tokens = append(tokens, fmt.Sprintf("error %d %d %q", notFound.Line, notFound.Column, notFound.Pattern))
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		name := token.Name
+		if token.Rule == runtime.INVALID_UTF8 {
+			name = fmt.Sprint(runtime.INVALID_UTF8)
+		}
+		tokens = append(tokens, fmt.Sprintf("%s %q %d", name, token.String(), token.Offset))
+	}
+	return tokens
+}
+
+// When the DFA gets stuck, the lexer must go back to the last final state,
+// even if it read many runes after it.
+func TestMaximalMunchBacktracking(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	// Each "a" is only found after reading up to the "x", looking for "a+b"
+	manyA := make([]string, 0, 41)
+	for i := range 40 {
+		manyA = append(manyA, fmt.Sprintf(`1 "a" %d`, i))
+	}
+	manyA = append(manyA, `2 "x" 40`)
+
+	cases := []struct {
+		name     string
+		rules    string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "one rune back",
+			rules:    "abc  { return 0 }\na  { return 1 }\n[a-z]  { return 2 }\n",
+			input:    "abxabcab",
+			expected: []string{`1 "a" 0`, `2 "b" 1`, `2 "x" 2`, `0 "abc" 3`, `1 "a" 6`, `2 "b" 7`},
+		},
+		{
+			name:     "many runes back",
+			rules:    "abcde  { return 0 }\nab  { return 1 }\n[a-z]  { return 2 }\n",
+			input:    "abcdxabcde",
+			expected: []string{`1 "ab" 0`, `2 "c" 2`, `2 "d" 3`, `2 "x" 4`, `0 "abcde" 5`},
+		},
+		{
+			name:     "more runes than the buffer holds at first",
+			rules:    "a+b  { return 0 }\na  { return 1 }\n[a-z]  { return 2 }\n",
+			input:    strings.Repeat("a", 40) + "x",
+			expected: manyA,
+		},
+		{
+			name:     "offsets of multibyte runes",
+			rules:    "ñandú  { return 0 }\nñ  { return 1 }\n[a-zá-ú]  { return 2 }\n",
+			input:    "ñanxñandú",
+			expected: []string{`1 "ñ" 0`, `2 "a" 2`, `2 "n" 3`, `2 "x" 4`, `0 "ñandú" 5`},
+		},
+		{
+			name:     "trailing context not found",
+			rules:    "ab/cd  { return 0 }\n[a-z]  { return 1 }\n",
+			input:    "abcxabcd",
+			expected: []string{`1 "a" 0`, `1 "b" 1`, `1 "c" 2`, `1 "x" 3`, `0 "ab" 4`, `1 "c" 6`, `1 "d" 7`},
+		},
+		{
+			name:     "trailing context that overlaps the pattern",
+			rules:    "x+/x  { return 0 }\n[a-z]  { return 1 }\n",
+			input:    "xxxy",
+			expected: []string{`0 "xx" 0`, `1 "x" 2`, `1 "y" 3`},
+		},
+		{
+			name:     "trailing context of multibyte runes",
+			rules:    "[a-zñ]+/ñ  { return 0 }\n[a-zñ]  { return 1 }\n",
+			input:    "añññb",
+			expected: []string{`0 "aññ" 0`, `1 "ñ" 5`, `1 "b" 7`},
+		},
+		{
+			name:     "no rule recognizes the input",
+			rules:    "abc  { return 0 }\n\\n  { return 1 }\n",
+			input:    "abc\nabcab",
+			expected: []string{`0 "abc" 0`, `1 "\n" 3`, `0 "abc" 4`, `error 2 4 "ab"`},
+		},
+		{
+			name:     "lexemes longer than the buffer",
+			rules:    "[a-z]+  { return 0 }\n\" \"  { return 1 }\n",
+			input:    strings.Repeat("a", 5000) + " " + strings.Repeat("b", 9000),
+			expected: []string{fmt.Sprintf(`0 %q 0`, strings.Repeat("a", 5000)), `1 " " 5000`, fmt.Sprintf(`0 %q 5001`, strings.Repeat("b", 9000))},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokens := runLexer(t, c.rules, c.input, INVALID_UTF8_ERROR)
+			if !slices.Equal(tokens, c.expected) {
+				t.Errorf("expected %v\ngot %v", c.expected, tokens)
+			}
+		})
+	}
+}
+
+// Lexemes of actions without return, or that return SKIP_LEXEME, are skipped.
+func TestSkippedLexemes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	rules := "\" \"+  { return SKIP_LEXEME }\n\\n  {}\n[a-z]+  { return 0 }\n"
+	expected := []string{`0 "ab" 0`, `0 "cd" 4`, `0 "ef" 7`}
+	if tokens := runLexer(t, rules, "ab  cd\nef", INVALID_UTF8_ERROR); !slices.Equal(tokens, expected) {
+		t.Errorf("expected %v\ngot %v", expected, tokens)
+	}
+}
+
+// Rules that recognize the empty string only recognize longer lexemes, so the lexer
+// doesn't return empty tokens at the same offset forever.
+func TestEmptyLexemes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	rules := "a*  { return 0 }\nb*/c  { return 1 }\n"
+	expected := []string{`0 "aa" 0`, `1 "bb" 2`, `error 1 5 "c"`}
+	if tokens := runLexer(t, rules, "aabbc", INVALID_UTF8_ERROR); !slices.Equal(tokens, expected) {
+		t.Errorf("expected %v\ngot %v", expected, tokens)
+	}
+}
+
+// Bytes that are not valid UTF-8 are returned as INVALID_UTF8 tokens (-3),
+// or read as U+FFFD if the lexer replaces them.
+func TestInvalidUTF8(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	cases := []struct {
+		name        string
+		rules       string
+		input       string
+		invalidUTF8 InvalidUTF8Policy
+		expected    []string
+	}{
+		{
+			name:        "error tokens",
+			rules:       "[a-z]+  { return 0 }\n[^\\n]  { return 1 }\n",
+			input:       "ab\xffcd\xc3",
+			invalidUTF8: INVALID_UTF8_ERROR,
+			expected:    []string{`0 "ab" 0`, `-3 "\xff" 2`, `0 "cd" 3`, `-3 "\xc3" 5`},
+		},
+		{
+			name:        "replaced",
+			rules:       "[a-z]+  { return 0 }\n[^\\n]  { return 1 }\n",
+			input:       "ab\xffcd\xc3",
+			invalidUTF8: INVALID_UTF8_REPLACE,
+			expected:    []string{`0 "ab" 0`, `1 "\xff" 2`, `0 "cd" 3`, `1 "\xc3" 5`},
+		},
+		{
+			name:        "replaced within a lexeme",
+			rules:       "[^ ]+  { return 0 }\n\" \"  { return 1 }\n",
+			input:       "a\xe2\x82b c",
+			invalidUTF8: INVALID_UTF8_REPLACE,
+			expected:    []string{`0 "a\xe2\x82b" 0`, `1 " " 4`, `0 "c" 5`},
+		},
+		{
+			name:        "replaced but no rule recognizes it",
+			rules:       "[a-z]+  { return 0 }\n",
+			input:       "a\xffb",
+			invalidUTF8: INVALID_UTF8_REPLACE,
+			expected:    []string{`0 "a" 0`, `-3 "\xff" 1`, `0 "b" 2`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokens := runLexer(t, c.rules, c.input, c.invalidUTF8)
+			if !slices.Equal(tokens, c.expected) {
+				t.Errorf("expected %v\ngot %v", c.expected, tokens)
+			}
+		})
+	}
+}
+
+// The input is decoded to UTF-8, but offsets and columns are counted in bytes of the input.
+func TestInputEncodings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	rules := "[a-zñ🙂]+  { return 0 }\n\" \"  { return 1 }\n\\n  { return 2 }\n"
+
+	// The input is decoded in chunks, that split the surrogate pairs of some runes.
+	// Offsets start after the byte order mark.
+	manyRunes := ""
+	manyTokens := make([]string, 0, 2000)
+	for i := range 1000 {
+		manyRunes += "=\xd8\x42\xde \x00"
+		manyTokens = append(manyTokens, fmt.Sprintf(`0 "🙂" %d`, 2+6*i), fmt.Sprintf(`1 " " %d`, 2+6*i+4))
+	}
+	cases := []struct {
+		name     string
+		input    string
+		encoding string
+		expected []string
+	}{
+		{
+			name:     "UTF-16LE",
+			input:    "a\x00\xf1\x00 \x00=\xd8\x42\xdeb\x00",
+			encoding: "UTF16LE",
+			expected: []string{`0 "añ" 0`, `1 " " 4`, `0 "🙂b" 6`},
+		},
+		{
+			name:     "byte order mark of UTF-16BE",
+			input:    "\xfe\xff\x00a\x00 \x00\n\x00b",
+			encoding: "UTF16LE",
+			expected: []string{`0 "a" 2`, `1 " " 4`, `2 "\n" 6`, `0 "b" 8`},
+		},
+		{
+			name:     "UTF-16 invalid surrogate",
+			input:    "\x00a\xd8\x3d\x00 ",
+			encoding: "UTF16BE",
+			expected: []string{`0 "a" 0`, "error 1 3 \"\uFFFD\""},
+		},
+		{
+			name:     "runes split between chunks",
+			input:    "\xff\xfe" + manyRunes,
+			encoding: "UTF16LE",
+			expected: manyTokens,
+		},
+		{
+			name:     "ISO-8859-1",
+			input:    "a\xf1o\nb\xff",
+			encoding: "LATIN1",
+			expected: []string{`0 "año" 0`, `2 "\n" 3`, `0 "b" 4`, `error 2 2 "ÿ"`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tokens := runLexerWithEncoding(t, rules, c.input, INVALID_UTF8_ERROR, c.encoding)
+			if !slices.Equal(tokens, c.expected) {
+				t.Errorf("expected %v\ngot %v", c.expected, tokens)
+			}
+		})
+	}
+}
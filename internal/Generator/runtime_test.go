@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Generates the lexer of testdata/runtime and runs its tests and benchmarks, which
+// check that reading tokens does not allocate. Use -v to see the benchmark results.
+func TestGeneratedLexerAllocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs a generated lexer")
+	}
+	source, err := filepath.Abs("testdata/runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"spec.lex", "main.go", "lexer_test.go"} {
+		content, err := os.ReadFile(filepath.Join(source, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module lexer\n\ngo 1.23\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Compile(filepath.Join(dir, "spec.lex"), filepath.Join(dir, "lexer.go"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "test", "-bench", ".", "-benchmem", "-benchtime", "20x")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	t.Logf("%s", output)
+}
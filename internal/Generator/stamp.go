@@ -0,0 +1,167 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+)
+
+// Generated lexers start with a stamp of the definition and the generator that wrote
+// them, so a lexer checked in can be told apart from a stale one:
+//
+//	// Code generated by yaalex 0.1.0 from calc.lex. DO NOT EDIT.
+//	// yaalex:stamp version=0.1.0 sha256=9f86d0... target=go package=main construction=direct invalid-utf8=error spec=calc.lex
+//
+// Comments start with "//" in every target. The first line is the one Go tools
+// look for to recognize generated files. Lexers generated from stdin, or written to
+// stdout, have no path to their definition: spec=- and no "from" in the first line.
+
+// Version of the generator, written in the stamp of the lexers. A lexer generated by
+// another version is stale, since it may be written differently.
+const VERSION = "0.1.0"
+
+const stampPrefix = "// yaalex:stamp "
+
+// Path of the definition in stamps without one.
+const noSpec = "-"
+
+// The stamp of a lexer has no definition to check it against, and none was given.
+var ErrNoSpec = errors.New("its stamp has no path to the definition, it was generated from stdin or to stdout")
+
+// Where a lexer comes from: the definition, the generator and the options that change its code.
+type Stamp struct {
+	Version      string
+	Hash         string // SHA-256 of the definition, in hex
+	Target       Lex_writer.Target
+	Package      string // Package of Go lexers, empty for the other targets
+	Construction dfa.Construction
+	InvalidUTF8  InvalidUTF8Policy
+	Spec         string // Path of the definition, relative to the directory of the lexer. Empty if unknown
+}
+
+// Returns the SHA-256 of a definition, in hex.
+func SpecHash(spec []byte) string {
+	hash := sha256.Sum256(spec)
+	return hex.EncodeToString(hash[:])
+}
+
+// Returns the lines of the stamp, with the newline at their end.
+func (s Stamp) String() string {
+	target := "target=" + string(s.Target)
+	if s.Package != "" {
+		target += " package=" + s.Package
+	}
+	from, spec := "", noSpec
+	if s.Spec != "" {
+		from, spec = " from "+filepath.Base(s.Spec), filepath.ToSlash(s.Spec)
+	}
+	return fmt.Sprintf("// Code generated by yaalex %s%s. DO NOT EDIT.\n", s.Version, from) +
+		fmt.Sprintf("%sversion=%s sha256=%s %s construction=%s invalid-utf8=%s spec=%s\n",
+			stampPrefix, s.Version, s.Hash, target, s.Construction, s.InvalidUTF8, spec)
+}
+
+// Returns the stamp at the start of a generated lexer.
+func ReadStamp(lexer []byte) (*Stamp, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(lexer))
+	for line := 0; line < 5 && scanner.Scan(); line++ {
+		fields, found := strings.CutPrefix(scanner.Text(), stampPrefix)
+		if !found {
+			continue
+		}
+
+		stamp := &Stamp{}
+		// The path of the definition is last, since it may have spaces
+		fields, spec, hasSpec := strings.Cut(fields, " spec=")
+		if spec != noSpec {
+			stamp.Spec = filepath.FromSlash(spec)
+		}
+		for _, field := range strings.Fields(fields) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "version":
+				stamp.Version = value
+			case "sha256":
+				stamp.Hash = value
+			case "target":
+				stamp.Target = Lex_writer.Target(value)
+			case "package":
+				stamp.Package = value
+			case "construction":
+				stamp.Construction = dfa.Construction(value)
+			case "invalid-utf8":
+				stamp.InvalidUTF8 = InvalidUTF8Policy(value)
+			}
+		}
+		if stamp.Version == "" || stamp.Hash == "" || !hasSpec || spec == "" {
+			return nil, fmt.Errorf("invalid stamp %q", scanner.Text())
+		}
+		return stamp, nil
+	}
+	return nil, errors.New("there is no yaalex stamp at the start of the lexer")
+}
+
+// A generated lexer that doesn't match its definition or the generator.
+type StaleError struct {
+	Lexer  string
+	Reason string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%s is stale: %s", e.Lexer, e.Reason)
+}
+
+// Checks the lexer at lexerPath is the one the generator writes now from its definition,
+// with the options in its stamp. The definition is specPath, or the one in the stamp if
+// empty. Returns a *StaleError if it is not, and ErrNoSpec if there is no definition to check.
+func Verify(lexerPath, specPath string) error {
+	lexer, err := os.ReadFile(lexerPath)
+	if err != nil {
+		return err
+	}
+	stamp, err := ReadStamp(lexer)
+	if err != nil {
+		return &StaleError{Lexer: lexerPath, Reason: err.Error()}
+	}
+	if specPath == "" {
+		if stamp.Spec == "" {
+			return fmt.Errorf("%s: %w", lexerPath, ErrNoSpec)
+		}
+		specPath = filepath.Join(filepath.Dir(lexerPath), stamp.Spec)
+	}
+	spec, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+
+	if SpecHash(spec) != stamp.Hash {
+		return &StaleError{Lexer: lexerPath, Reason: fmt.Sprintf("%s changed after it was generated", specPath)}
+	}
+	if stamp.Version != VERSION {
+		return &StaleError{Lexer: lexerPath, Reason: fmt.Sprintf("it was generated by yaalex %s, this is %s", stamp.Version, VERSION)}
+	}
+
+	var expected bytes.Buffer
+	err = Generate(bytes.NewReader(spec), specPath, &expected, Options{
+		Construction: stamp.Construction,
+		Target:       stamp.Target,
+		Package:      stamp.Package,
+		InvalidUTF8:  stamp.InvalidUTF8,
+		SpecPath:     stamp.Spec,
+	})
+	if err != nil {
+		return fmt.Errorf("generating %s again: %w", lexerPath, err)
+	}
+	if !bytes.Equal(expected.Bytes(), lexer) {
+		return &StaleError{Lexer: lexerPath, Reason: "it was edited after it was generated"}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+)
+
+func TestVerifyFindsStaleLexers(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "specs", "calc.lex")
+	lexerPath := filepath.Join(dir, "lexer.c")
+	spec := "%{\n%}\n{\n}\n%%\n[0-9]+  { return 1; }\n%%\n"
+	if err := os.Mkdir(filepath.Dir(specPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(specPath, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compile(specPath, lexerPath, Options{Target: Lex_writer.C_TARGET, InvalidUTF8: INVALID_UTF8_REPLACE}); err != nil {
+		t.Fatal(err)
+	}
+	lexer, err := os.ReadFile(lexerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stamp, err := ReadStamp(lexer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.Spec != filepath.Join("specs", "calc.lex") || stamp.Hash != SpecHash([]byte(spec)) || stamp.Target != Lex_writer.C_TARGET {
+		t.Errorf("unexpected stamp %+v", stamp)
+	}
+	if err := Verify(lexerPath, ""); err != nil {
+		t.Fatalf("expected the lexer to be up to date, got %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		lexer  string
+		spec   string
+		reason string
+	}{
+		{"spec changed", string(lexer), strings.Replace(spec, "[0-9]+", "[0-9]*", 1), "changed after it was generated"},
+		{"other version", strings.Replace(string(lexer), "version="+VERSION, "version=0.0.1", 1), spec, "generated by yaalex 0.0.1"},
+		{"edited", string(lexer) + "\nint answer = 42;\n", spec, "edited after it was generated"},
+		{"no stamp", string(lexer[len(stamp.String()):]), spec, "no yaalex stamp"},
+	}
+	for _, c := range cases {
+		if err := os.WriteFile(lexerPath, []byte(c.lexer), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(specPath, []byte(c.spec), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var stale *StaleError
+		if err := Verify(lexerPath, ""); !errors.As(err, &stale) || !strings.Contains(stale.Reason, c.reason) {
+			t.Errorf("%s: expected the lexer to be stale because %q, got %v", c.name, c.reason, err)
+		}
+	}
+}
+
+// Go lexers are written in the package of the stamp, so they build outside of main packages.
+func TestVerifyChecksThePackage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a generated lexer")
+	}
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "calc.lex")
+	lexerPath := filepath.Join(dir, "lexer.go")
+	files := map[string]string{
+		"calc.lex": "%{\nconst NUMBER = 1\n%}\n%%\n[0-9]+  { return NUMBER }\n%%\n",
+		"go.mod":   "module calc\n\ngo 1.23\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Compile(specPath, lexerPath, Options{Package: "calc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compile(specPath, filepath.Join(dir, "other.go"), Options{Package: "1calc"}); err == nil {
+		t.Errorf("expected an error for an invalid package name")
+	}
+
+	cmd := exec.Command("go", "build", ".")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	lexer, err := os.ReadFile(lexerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp, err := ReadStamp(lexer); err != nil || stamp.Package != "calc" {
+		t.Fatalf("expected the stamp to have the package calc, got %+v %v", stamp, err)
+	}
+	if err := Verify(lexerPath, ""); err != nil {
+		t.Fatalf("expected the lexer to be up to date, got %v", err)
+	}
+
+	edited := strings.Replace(string(lexer), "package calc", "package main", 1)
+	if err := os.WriteFile(lexerPath, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stale *StaleError
+	if err := Verify(lexerPath, ""); !errors.As(err, &stale) {
+		t.Errorf("expected the lexer with another package to be stale, got %v", err)
+	}
+}
+
+// Lexers generated from stdin or to stdout have no definition in their stamp.
+func TestVerifyWithoutSpec(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "calc.lex")
+	lexerPath := filepath.Join(dir, "lexer.go")
+	spec := "%{\n%}\n{\n}\n%%\n[0-9]+  { return 1 }\n%%\n"
+	if err := os.WriteFile(specPath, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var lexer bytes.Buffer
+	if err := Generate(strings.NewReader(spec), "<stdin>", &lexer, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lexerPath, lexer.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stamp, err := ReadStamp(lexer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp.Spec != "" || !strings.Contains(stamp.String(), " spec=-\n") || strings.Contains(stamp.String(), "<stdin>") {
+		t.Errorf("expected a stamp without a definition, got %q", stamp.String())
+	}
+	if err := Verify(lexerPath, ""); !errors.Is(err, ErrNoSpec) {
+		t.Errorf("expected ErrNoSpec, got %v", err)
+	}
+	if err := Verify(lexerPath, specPath); err != nil {
+		t.Errorf("expected the lexer to be up to date with the definition given, got %v", err)
+	}
+}
+
+func TestRelativeSpecPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		spec, output, expected string
+	}{
+		{"calc.lex", "lexer.go", "calc.lex"},
+		{filepath.Join("specs", "calc.lex"), filepath.Join("gen", "lexer.go"), filepath.Join("..", "specs", "calc.lex")},
+		{filepath.Join(wd, "calc.lex"), filepath.Join("gen", "lexer.go"), filepath.Join("..", "calc.lex")},
+		{"calc.lex", filepath.Join(wd, "lexer.go"), "calc.lex"},
+	}
+	for _, c := range cases {
+		if relative := RelativeSpecPath(c.spec, c.output); relative != c.expected {
+			t.Errorf("RelativeSpecPath(%q, %q): expected %q, got %q", c.spec, c.output, c.expected, relative)
+		}
+	}
+}
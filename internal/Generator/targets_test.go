@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	Lex_writer "github.com/DanielRasho/Lexer/internal/Generator/LexWriter"
+	"github.com/DanielRasho/Lexer/runtime"
+)
+
+// Rules valid in C and TypeScript. Each action returns its token id, except the one of spaces.
+// The last one recognizes the empty string, which is never a lexeme.
+// Ids start at 1, since yylex returns 0 at the end of the input.
+const targetRules = `^#[a-z]+  { return 1; }
+[a-zñ]+  { return 2; }
+[0-9]+/x  { return 3; }
+[0-9]+  { return 4; }
+\n  { return 5; }
+" "  {}
+y*  { return 6; }
+`
+
+// Prints every token of the input read from stdin, one per line: "ID OFFSET HEX-LEXEME".
+// Then, if the input has an invalid lexeme, its position: "error LINE COLUMN HEX-PATTERN"
+var targetMains = map[Lex_writer.Target]struct{ header, footer string }{
+	Lex_writer.C_TARGET: {footer: `
+static void print_hex(void) {
+	int i;
+	for (i = 0; i < yyleng; i++) printf("%02x", (unsigned char)yytext[i]);
+	printf("\n");
+}
+
+int main(void) {
+	int token;
+	while ((token = yylex()) != YY_EOF) {
+		if (token == NO_LEXEME) {
+			printf("error %d %d ", yylineno, yycolumn);
+			print_hex();
+			return 0;
+		}
+		printf("%d %ld ", token, yyoffset);
+		print_hex();
+	}
+	return 0;
+}
+`},
+	Lex_writer.TYPESCRIPT_TARGET: {header: `import { readFileSync } from "node:fs";`, footer: `
+const hex = (text: string) => Buffer.from(text).toString("hex");
+const lexer = new Lexer(new Uint8Array(readFileSync(0)));
+try {
+  for (let token = lexer.nextToken(); token !== null; token = lexer.nextToken()) {
+    console.log(token.tokenId + " " + token.offset + " " + hex(token.lexeme));
+  }
+} catch (e) {
+  if (!(e instanceof PatternNotFound)) throw e;
+  console.log("error " + e.line + " " + e.column + " " + hex(e.pattern));
+}
+`},
+}
+
+// Commands that build and run a lexer in its directory, or nil and why if the tools are not installed.
+func targetCommands(target Lex_writer.Target) ([][]string, string) {
+	switch target {
+	case Lex_writer.C_TARGET:
+		if _, err := exec.LookPath("cc"); err != nil {
+			return nil, "there is no C compiler (cc)"
+		}
+		return [][]string{{"cc", "-std=c99", "-Wall", "-Werror", "-o", "lexer", "lexer.c"}, {"./lexer"}}, ""
+	case Lex_writer.TYPESCRIPT_TARGET:
+		// Node runs TypeScript since version 22.6
+		if _, err := exec.LookPath("node"); err != nil {
+			return nil, "there is no node"
+		}
+		if err := exec.Command("node", "--experimental-strip-types", "-e", "").Run(); err != nil {
+			version, _ := exec.Command("node", "--version").Output()
+			return nil, fmt.Sprintf("node %s doesn't run TypeScript, it needs 22.6 or later", strings.TrimSpace(string(version)))
+		}
+		return [][]string{{"node", "--experimental-strip-types", "--no-warnings", "lexer.ts"}}, ""
+	}
+	return nil, fmt.Sprintf("there are no tools to run %s", target)
+}
+
+// Lexers in other languages find the same tokens as the runtime, which finds the ones of
+// the lexers in Go.
+func TestTargetsFindTheSameTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs generated lexers")
+	}
+
+	inputs := []string{
+		"#abc ab 12x ñu\n#if 9\n#",
+		"a 1\n2 ?",
+		"yy a?",
+		strings.Repeat("abc 123 ", 2000),
+	}
+
+	for _, target := range []Lex_writer.Target{Lex_writer.C_TARGET, Lex_writer.TYPESCRIPT_TARGET} {
+		t.Run(string(target), func(t *testing.T) {
+			dir := t.TempDir()
+			commands, reason := targetCommands(target)
+			if commands == nil {
+				t.Skip(reason)
+			}
+			spec := "%{\n" + targetMains[target].header + "\n%}\n{\n}\n%%\n" + targetRules + "%%\n%{\n" + targetMains[target].footer + "%}\n"
+			if err := os.WriteFile(filepath.Join(dir, "spec.lex"), []byte(spec), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			output := map[Lex_writer.Target]string{Lex_writer.C_TARGET: "lexer.c", Lex_writer.TYPESCRIPT_TARGET: "lexer.ts"}[target]
+			options := Options{Target: target, Automaton: filepath.Join(dir, "automaton.json")}
+			if err := Compile(filepath.Join(dir, "spec.lex"), filepath.Join(dir, output), options); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, command := range commands[:len(commands)-1] {
+				build := exec.Command(command[0], command[1:]...)
+				build.Dir = dir
+				if output, err := build.CombinedOutput(); err != nil {
+					t.Fatalf("%v\n%s", err, output)
+				}
+			}
+			for _, input := range inputs {
+				run := exec.Command(commands[len(commands)-1][0], commands[len(commands)-1][1:]...)
+				run.Dir = dir
+				run.Stdin = strings.NewReader(input)
+				output, err := run.CombinedOutput()
+				if err != nil {
+					t.Fatalf("%v\n%s", err, output)
+				}
+				tokens := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+
+				expected := runtimeHexTokens(t, options.Automaton, input)
+				if !slices.Equal(tokens, expected) {
+					t.Errorf("on %.40q expected %.10v\ngot %.10v", input, expected, tokens)
+				}
+			}
+		})
+	}
+}
+
+// TypeScript lexers type check with any version of Node, even the ones that can't run them.
+// The lexer has no main, since it would need the types of Node.
+func TestTypeScriptLexerTypeChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("type checks a generated lexer")
+	}
+	if _, err := exec.LookPath("tsc"); err != nil {
+		t.Skip("there is no TypeScript compiler (tsc)")
+	}
+	dir := t.TempDir()
+	spec := "%{\n%}\n{\n}\n%%\n" + targetRules + "%%\n"
+	if err := os.WriteFile(filepath.Join(dir, "spec.lex"), []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, policy := range []InvalidUTF8Policy{INVALID_UTF8_ERROR, INVALID_UTF8_REPLACE} {
+		options := Options{Target: Lex_writer.TYPESCRIPT_TARGET, InvalidUTF8: policy}
+		if err := Compile(filepath.Join(dir, "spec.lex"), filepath.Join(dir, "lexer.ts"), options); err != nil {
+			t.Fatal(err)
+		}
+		check := exec.Command("tsc", "--noEmit", "--strict", "--target", "es2022", "lexer.ts")
+		check.Dir = dir
+		if output, err := check.CombinedOutput(); err != nil {
+			t.Errorf("invalid-utf8=%s: %v\n%s", policy, err, output)
+		}
+	}
+}
+
+// Same as runRuntime, printing the lexemes as the lexers of other targets do.
+func runtimeHexTokens(t *testing.T, path, input string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner, err := runtime.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner.Reset(strings.NewReader(input))
+
+	tokens := make([]string, 0)
+	for {
+		token, err := scanner.GetNextToken()
+		if err == io.EOF {
+			return tokens
+		} else if notFound, ok := err.(*runtime.PatternNotFound); ok {
+			return append(tokens, fmt.Sprintf("error %d %d %x", notFound.Line, notFound.Column, notFound.Pattern))
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, fmt.Sprintf("%s %d %x", token.Name, token.Offset, token.Bytes()))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// Returns lines like the ones of the logs of a service.
+func logInput(lines int) []byte {
+	var buffer bytes.Buffer
+	levels := []string{"INFO", "WARN", "ERROR", "DEBUG"}
+	for i := range lines {
+		fmt.Fprintf(&buffer, "2024-03-%02dT10:%02d:%02dZ %s [worker-%d] request from 10.0.%d.%d path=\"/api/v1/items/%d\" took %d.%03dms status=%d\n",
+			i%28+1, i%60, (i*7)%60, levels[i%len(levels)], i%8, i%256, (i*13)%256, i, i%500, i%1000, 200+i%4)
+	}
+	return buffer.Bytes()
+}
+
+func BenchmarkGetNextToken(b *testing.B) {
+	input := logInput(1000)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for range b.N {
+		lexer := NewLexerFromReader(bytes.NewReader(input))
+		for {
+			_, err := lexer.GetNextToken()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Tokens are slices of the buffer of the lexer, so reading them does not allocate.
+func TestGetNextTokenDoesNotAllocate(t *testing.T) {
+	lexer := NewLexerFromReader(bytes.NewReader(logInput(1000)))
+	allocs := testing.AllocsPerRun(5000, func() {
+		if _, err := lexer.GetNextToken(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations per token, got %v", allocs)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Prints the number of tokens of a file.
+func main() {
+	lexer, err := NewLexer(os.Args[1])
+	if err != nil {
+		panic(err)
+	}
+	defer lexer.Close()
+	count := 0
+	for {
+		_, err := lexer.GetNextToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		count++
+	}
+	fmt.Println(count)
+}
@@ -2,6 +2,7 @@ package io
 
 import (
 	"bufio"
+	"io"
 	"os"
 )
 
@@ -23,6 +24,11 @@ func ReadFile(path string) (*FileReader, error) {
 	}, nil
 }
 
+// NewReader returns a FileReader of an already open reader. Closing it does nothing.
+func NewReader(r io.Reader) *FileReader {
+	return &FileReader{reader: bufio.NewReader(r)}
+}
+
 // NextLine reads the next line from the file and stores it in the provided string pointer
 func (fr *FileReader) NextLine(line *string) bool {
 	str, err := fr.reader.ReadString('\n')
@@ -44,6 +50,9 @@ func (fr *FileReader) NextChar(char *rune) bool {
 
 // IMPORTANT: dont forget to close the file once to ended reading it!
 func (fr *FileReader) Close() error {
+	if fr.file == nil {
+		return nil
+	}
 	return fr.file.Close()
 }
 
@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding of the input of a Scanner.
+type Encoding int
+
+const (
+	UTF8    Encoding = iota // Read without decoding
+	UTF16LE                 // A byte order mark at the start is skipped, and sets the byte order
+	UTF16BE                 // A byte order mark at the start is skipped, and sets the byte order
+	LATIN1                  // ISO-8859-1, each byte is a rune
+)
+
+// Reader that decodes its input to UTF-8. Invalid input is decoded as U+FFFD.
+type decoder struct {
+	reader   io.Reader
+	encoding Encoding
+	chunk    []byte // Bytes read from reader
+	input    []byte // Bytes read not decoded yet
+	output   []byte // Bytes decoded not returned yet
+	err      error  // Error of the last read
+	started  bool   // If the byte order mark was checked
+	skipped  int    // No of bytes of the byte order mark, not returned
+}
+
+func newDecoder(reader io.Reader, encoding Encoding) *decoder {
+	return &decoder{reader: reader, encoding: encoding, chunk: make([]byte, BUFFER_SIZE)}
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.output) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.decode()
+	}
+	n := copy(p, d.output)
+	d.output = d.output[n:]
+	return n, nil
+}
+
+// Reads a chunk of the input and decodes all its complete runes.
+func (d *decoder) decode() {
+	n, err := d.reader.Read(d.chunk)
+	d.input = append(d.input, d.chunk[:n]...)
+	if err != nil {
+		d.err = err
+	}
+
+	output := d.output[:0]
+	input := d.input
+	switch d.encoding {
+	case LATIN1:
+		for _, b := range input {
+			output = utf8.AppendRune(output, rune(b))
+		}
+		input = input[len(input):]
+	case UTF16LE, UTF16BE:
+		if !d.started && (len(input) >= 2 || d.err != nil) {
+			d.started = true
+			if len(input) >= 2 && input[0] == 0xFF && input[1] == 0xFE {
+				d.encoding, d.skipped, input = UTF16LE, 2, input[2:]
+			} else if len(input) >= 2 && input[0] == 0xFE && input[1] == 0xFF {
+				d.encoding, d.skipped, input = UTF16BE, 2, input[2:]
+			}
+		}
+		for d.started && len(input) >= 2 {
+			r := rune(d.unit(input))
+			size := 2
+			if utf16.IsSurrogate(r) && len(input) < 4 && d.err == nil {
+				break // Wait for the rest of the pair
+			}
+			if utf16.IsSurrogate(r) && len(input) >= 4 {
+				if pair := utf16.DecodeRune(r, rune(d.unit(input[2:]))); pair != utf8.RuneError {
+					r, size = pair, 4
+				}
+			}
+			if utf16.IsSurrogate(r) {
+				r = utf8.RuneError
+			}
+			output = utf8.AppendRune(output, r)
+			input = input[size:]
+		}
+		// An odd byte at the end
+		if d.err != nil && len(input) == 1 {
+			output = utf8.AppendRune(output, utf8.RuneError)
+			input = input[1:]
+		}
+	}
+	d.input = append(d.input[:0], input...)
+	d.output = output
+}
+
+// Returns the first code unit of UTF-16 input.
+func (d *decoder) unit(input []byte) uint16 {
+	if d.encoding == UTF16LE {
+		return uint16(input[0]) | uint16(input[1])<<8
+	}
+	return uint16(input[0])<<8 | uint16(input[1])
+}
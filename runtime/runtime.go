@@ -0,0 +1,441 @@
+// Package runtime tokenizes input with the automaton of a lexer, as written by the
+// generator with -emit-automaton, instead of generating and compiling Go code. So lexers
+// can be defined while a program runs, without a Go toolchain.
+//
+// It recognizes the same tokens as the generated lexer, but it can't execute the Go
+// code of the actions. Tokens are returned with the rule that recognized them instead:
+//
+//	scanner, err := runtime.Load(automaton)
+//	scanner.Reset(reader)
+//	for {
+//		token, err := scanner.GetNextToken()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		fmt.Println(token.Name, token.String())
+//	}
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+)
+
+const NO_LEXEME = -1    // Rule of a lexeme that no rule recognizes
+const INVALID_UTF8 = -3 // Rule of bytes that are not valid UTF-8 and no rule recognizes
+
+const SKIP_LEXEME = "SKIP_LEXEME" // Name of the rules whose lexemes are skipped, as in the generated lexer
+
+const BUFFER_SIZE = 4096 // Initial size of the buffer of the scanner, it grows for longer lexemes
+
+const REPLACEMENT_CHAR = "\uFFFD"
+
+// PatternNotFound represents an error when a pattern is not found in the input
+type PatternNotFound struct {
+	Line    int
+	Column  int
+	Pattern string
+}
+
+// Error implements the error interface for PatternNotFound
+func (e *PatternNotFound) Error() string {
+	return fmt.Sprintf("error line %d column %d \n\tpattern not found. current pattern not recognized by the language: %s",
+		e.Line,
+		e.Column,
+		e.Pattern)
+}
+
+// Rule of the lexer, by priority.
+type Rule struct {
+	Name   string // What its action returns, if it is "{ return NAME }". Empty otherwise
+	Action string // Go code of the action, as written in the YALex file
+	Skip   bool   // If its action has no return or returns SKIP_LEXEME, so its lexemes are skipped as the generated lexer does
+}
+
+// Represents a piece of information within the input
+type Token struct {
+	Rule   int    // Rule (by priority) that recognized the lexeme, or INVALID_UTF8
+	Name   string // Name of the rule, empty for INVALID_UTF8 tokens
+	Offset int    // No of bytes of the input from the start of it to the lexeme
+	lexeme []byte // Actual bytes read by the scanner (in UTF-8), part of its buffer
+}
+
+// Returns the lexeme of the token without copying it. It is only valid until
+// the next call to GetNextToken, since the scanner reuses its buffer.
+func (t *Token) Bytes() []byte {
+	return t.lexeme
+}
+
+// Returns a copy of the lexeme of the token.
+func (t *Token) String() string {
+	return string(t.lexeme)
+}
+
+// Scanner that splits its input in tokens with a loaded automaton.
+type Scanner struct {
+	// How bytes that are not valid UTF-8 are read. If true, each one is read as U+FFFD,
+	// so rules like "[^a]" recognize it. Otherwise no rule recognizes them, and they are
+	// returned as INVALID_UTF8 tokens. Set it before reading.
+	ReplaceInvalidUTF8 bool
+
+	automata    *automaton
+	reader      io.Reader // Reader to get the symbols from, decoded to UTF-8
+	decoder     *decoder  // Decoding layer of reader, nil for UTF-8 input
+	encoding    Encoding  // Encoding of the input
+	readErr     error     // Error of the last read (io.EOF at the end), returned once the buffer is consumed
+	buffer      []byte    // Bytes read but not consumed yet, buffer[start:end] is the current lexeme
+	start       int       // Index in buffer where the current lexeme starts
+	end         int       // Index in buffer after the last byte of the current lexeme
+	position    int       // No of bytes of the input (before decoding) from its start to the current lexeme
+	line        int       // Line where the current lexeme starts
+	lineStart   int       // No of bytes of the input from its start to the start of line
+	headEnds    []int     // Lenght of the lexeme (in bytes) when the pattern before "/" of each rule ended
+	atLineStart bool      // If the next lexeme starts at the beginning of a line
+}
+
+// DFA over the bytes of UTF-8 input, as the one of the generated lexer.
+type automaton struct {
+	startState     *state
+	lineStartState *state // Start state at the beginning of a line
+	rules          []Rule
+	trailing       []bool     // If each rule has trailing context "r/s", so the lexeme ends before s
+	trailingLength []int      // Bytes of s of each rule with trailing context, -1 if its length is not fixed
+	classes        [256]uint8 // Class of each byte, bytes of the same class move to the same states
+}
+
+type state struct {
+	rule     int      // Rule recognized if the lexeme ends in this state, only set if isFinal
+	headEnds []int    // Rules with trailing context "r/s" whose "r" ends in this state
+	partial  bool     // If it is in the middle of the UTF-8 encoding of a rune
+	next     []*state // State reached with each class of bytes, nil if there is no transition
+	isFinal  bool
+}
+
+// Action that only returns a value. Ex: "{ return ID }"
+var returnAction = regexp.MustCompile(`^\{\s*return\s+([^\s;{}]+)\s*;?\s*\}$`)
+
+// Loads an automaton written in JSON or binary by the generator. The scanner reads
+// nothing until Reset is called.
+func Load(data []byte) (*Scanner, error) {
+	automata, err := dfa.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	byteDFA := dfa.CompileUTF8(automata)
+
+	loaded := &automaton{
+		rules:          make([]Rule, len(byteDFA.Rules)),
+		trailing:       make([]bool, len(byteDFA.Rules)),
+		trailingLength: make([]int, len(byteDFA.Rules)),
+	}
+	for i, action := range byteDFA.Rules {
+		code := strings.TrimSpace(action.Code)
+		rule := Rule{Action: code, Skip: !strings.Contains(code, "return")}
+		if match := returnAction.FindStringSubmatch(code); match != nil {
+			rule.Name = match[1]
+			rule.Skip = rule.Name == SKIP_LEXEME
+		}
+		loaded.rules[i] = rule
+		loaded.trailing[i] = action.TrailingContext
+		loaded.trailingLength[i] = action.TrailingLength
+	}
+	for b, class := range byteDFA.Classes {
+		loaded.classes[b] = uint8(class)
+	}
+
+	states := make(map[*dfa.ByteState]*state, len(byteDFA.States))
+	for _, st := range byteDFA.States {
+		states[st] = &state{
+			rule:     st.Rule,
+			headEnds: st.HeadEnds,
+			partial:  st.Partial,
+			next:     make([]*state, byteDFA.NumClasses),
+			isFinal:  st.IsFinal,
+		}
+	}
+	for _, st := range byteDFA.States {
+		for _, t := range st.Transitions {
+			for b := int(t.From); b <= int(t.To); b++ {
+				states[st].next[byteDFA.Classes[b]] = states[t.State]
+			}
+		}
+	}
+	loaded.startState = states[byteDFA.StartState]
+	loaded.lineStartState = states[byteDFA.LineStartState]
+
+	scanner := &Scanner{automata: loaded, buffer: make([]byte, 0, BUFFER_SIZE)}
+	scanner.Reset(bytes.NewReader(nil))
+	return scanner, nil
+}
+
+// Returns the rules of the lexer, by priority.
+func (s *Scanner) Rules() []Rule {
+	return s.automata.rules
+}
+
+// Starts reading UTF-8 input from reader, discarding what is left of the previous one.
+func (s *Scanner) Reset(reader io.Reader) {
+	s.ResetWithEncoding(reader, UTF8)
+}
+
+// Starts reading input in the given encoding from reader. The input is decoded to
+// UTF-8, so tokens are always UTF-8, but their offsets are counted in bytes of the input.
+func (s *Scanner) ResetWithEncoding(reader io.Reader, encoding Encoding) {
+	s.reader = reader
+	s.decoder = nil
+	s.encoding = encoding
+	if encoding != UTF8 {
+		s.decoder = newDecoder(reader, encoding)
+		s.reader = s.decoder
+	}
+	s.readErr = nil
+	s.buffer = s.buffer[:0]
+	s.start, s.end = 0, 0
+	s.position, s.line, s.lineStart = 0, 1, 0
+	s.headEnds = make([]int, len(s.automata.rules))
+	s.atLineStart = true
+}
+
+// GetNextToken return the next larger token that can find within the input
+// starting from the last position it was left. Returns io.EOF at its end.
+func (s *Scanner) GetNextToken() (Token, error) {
+	for {
+		// 1. Find the longest lexeme and the rule it matches
+		rule, err := s.longestMatch()
+		if err != nil {
+			return Token{}, err
+		}
+
+		token := Token{
+			Rule:   rule,
+			Offset: s.position,
+			lexeme: s.buffer[s.start:s.end:s.end],
+		}
+		s.consumeLexeme()
+
+		// 2. Skipped lexemes are not returned, the scanner continues with the next one
+		if rule == INVALID_UTF8 {
+			return token, nil
+		}
+		if s.automata.rules[rule].Skip {
+			continue
+		}
+		token.Name = s.automata.rules[rule].Name
+		return token, nil
+	}
+}
+
+// Reads bytes moving through the DFA while it can (maximal munch). Then, the bytes
+// read after the last final state are returned to the input, so the current lexeme
+// is the longest one recognized.
+// Returns the rule recognized, the one with highest priority if many, or INVALID_UTF8
+// if the lexeme is a byte that is not valid UTF-8.
+func (s *Scanner) longestMatch() (int, error) {
+	currentState := s.startState()
+	lastRule := NO_LEXEME     // Rule of the last final state
+	lastLength := 0           // Lenght of the lexeme (in bytes) for the last final state
+	runeState := currentState // Last state reached at the end of a rune
+	runeLength := 0           // Lenght of the lexeme (in bytes) for runeState
+
+	for {
+		// States in the middle of the encoding of a rune are never final
+		if !currentState.partial {
+			runeState = currentState
+			runeLength = s.end - s.start
+			// 1. Remember where the pattern of rules with trailing context ends.
+			// Lengths are used instead of indexes, since the buffer moves when it is refilled.
+			for _, rule := range currentState.headEnds {
+				s.headEnds[rule] = runeLength
+			}
+			// 2. Remember the last final state, the longest lexeme recognized so far
+			if currentState.isFinal {
+				length := runeLength
+				if s.automata.trailing[currentState.rule] {
+					// The lexeme ends before the trailing context. If its length is not
+					// fixed, where the head of the rule ended last.
+					if trailing := s.automata.trailingLength[currentState.rule]; trailing >= 0 {
+						length = max(runeLength-trailing, 0)
+					} else {
+						length = s.headEnds[currentState.rule]
+					}
+				}
+				// Empty lexemes are not recognized, the next one would start at the same offset
+				if length > 0 {
+					lastRule = currentState.rule
+					lastLength = length
+				}
+			}
+		}
+
+		// 3. Read the next byte, it is added to the lexeme
+		b, err := s.readByte()
+		if err != nil && err != io.EOF {
+			if lastRule != NO_LEXEME {
+				break
+			}
+			return NO_LEXEME, err
+		}
+
+		// 4. Check if exist another state to jump to
+		if err == nil {
+			if nextState := currentState.next[s.automata.classes[b]]; nextState != nil {
+				currentState = nextState
+				continue
+			}
+		}
+
+		// 5. The DFA got stuck, or the input ended. The rune after runeState may not be valid UTF-8.
+		r, size := s.decodeRune(runeLength)
+		if r == utf8.RuneError && size == 1 {
+			if s.ReplaceInvalidUTF8 {
+				// Read the invalid byte as the replacement character
+				if replaced := s.move(runeState, REPLACEMENT_CHAR); replaced != nil {
+					currentState = replaced
+					s.end = s.start + runeLength + 1
+					continue
+				}
+			}
+			if runeLength == 0 {
+				s.end = s.start + 1
+				return INVALID_UTF8, nil
+			}
+		}
+
+		// return the last recognized lexeme
+		if lastRule != NO_LEXEME {
+			break
+		}
+		// Nothing else to read
+		if s.end == s.start {
+			return NO_LEXEME, err
+		}
+		// The input has invalid lexemes, the pattern ends with the rune no rule expected.
+		s.end = s.start + runeLength + size
+		return NO_LEXEME, s.patternNotFound()
+	}
+
+	// 6. Return to the input what was read after the last final state,
+	// the bytes are kept in the buffer to read them again.
+	s.end = s.start + lastLength
+	return lastRule, nil
+}
+
+// Returns the state reached from st with the bytes of input, nil if there is none.
+func (s *Scanner) move(st *state, input string) *state {
+	for i := 0; i < len(input) && st != nil; i++ {
+		st = st.next[s.automata.classes[input[i]]]
+	}
+	return st
+}
+
+// Returns the state to start recognizing a lexeme from.
+// Rules anchored with "^" can only match at the beginning of a line.
+func (s *Scanner) startState() *state {
+	if s.atLineStart {
+		return s.automata.lineStartState
+	}
+	return s.automata.startState
+}
+
+// Reads the next byte and adds it to the current lexeme.
+// The bytes returned to the input are read first, from the buffer.
+func (s *Scanner) readByte() (byte, error) {
+	for s.end == len(s.buffer) && s.readErr == nil {
+		s.fill()
+	}
+	if s.end == len(s.buffer) {
+		return 0, s.readErr
+	}
+	b := s.buffer[s.end]
+	s.end++
+	return b, nil
+}
+
+// Decodes the rune at the given position of the current lexeme, reading its bytes
+// if needed. Returns (RuneError, 1) if it is not valid UTF-8 and (RuneError, 0) at the end.
+func (s *Scanner) decodeRune(position int) (rune, int) {
+	for !utf8.FullRune(s.buffer[s.start+position:]) && s.readErr == nil {
+		s.fill()
+	}
+	return utf8.DecodeRune(s.buffer[s.start+position:])
+}
+
+// Reads more bytes into the buffer. The bytes before the current lexeme are not needed
+// anymore, so the lexeme is moved to the start of the buffer, which only grows if the
+// lexeme fills it.
+func (s *Scanner) fill() {
+	if s.start > 0 {
+		n := copy(s.buffer, s.buffer[s.start:])
+		s.buffer = s.buffer[:n]
+		s.end -= s.start
+		s.start = 0
+	}
+	if len(s.buffer) == cap(s.buffer) {
+		grown := make([]byte, len(s.buffer), 2*cap(s.buffer))
+		copy(grown, s.buffer)
+		s.buffer = grown
+	}
+	n, err := s.reader.Read(s.buffer[len(s.buffer):cap(s.buffer)])
+	s.buffer = s.buffer[:len(s.buffer)+n]
+	if err != nil {
+		s.readErr = err
+	}
+	// The byte order mark is not part of the first lexeme, but it is counted in its offset
+	if s.decoder != nil {
+		s.position += s.decoder.skipped
+		s.decoder.skipped = 0
+	}
+}
+
+// Discards the current lexeme once it was recognized.
+func (s *Scanner) consumeLexeme() {
+	lexeme := s.buffer[s.start:s.end]
+	if len(lexeme) > 0 {
+		s.atLineStart = lexeme[len(lexeme)-1] == '\n'
+	}
+	// Keep track of the lines, to report where errors are
+	for i := bytes.IndexByte(lexeme, '\n'); i >= 0; i = bytes.IndexByte(lexeme, '\n') {
+		s.line++
+		s.position += s.inputLength(lexeme[:i+1])
+		s.lineStart = s.position
+		lexeme = lexeme[i+1:]
+	}
+	s.position += s.inputLength(lexeme)
+	s.start = s.end
+}
+
+// Returns the no of bytes text had in the input, before it was decoded to UTF-8.
+func (s *Scanner) inputLength(text []byte) int {
+	switch s.encoding {
+	case LATIN1:
+		return utf8.RuneCount(text)
+	case UTF16LE, UTF16BE:
+		length := 0
+		for len(text) > 0 {
+			r, size := utf8.DecodeRune(text)
+			length += 2
+			if r > 0xFFFF {
+				length += 2 // Surrogate pair
+			}
+			text = text[size:]
+		}
+		return length
+	}
+	return len(text)
+}
+
+// Returns the error for the current lexeme, which no rule recognizes.
+// Its column is counted in bytes of the input from the start of the line.
+func (s *Scanner) patternNotFound() error {
+	return &PatternNotFound{
+		Line:    s.line,
+		Column:  s.position - s.lineStart + 1,
+		Pattern: string(s.buffer[s.start:s.end])}
+}
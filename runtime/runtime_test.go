@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	dfa "github.com/DanielRasho/Lexer/internal/DFA"
+	postfix "github.com/DanielRasho/Lexer/internal/DFA/Postfix"
+)
+
+// Returns the automaton of a lexer with the given patterns and actions, in the binary encoding.
+func automatonOf(t *testing.T, rules [][2]string) []byte {
+	options := make([]postfix.Regex, len(rules))
+	for i, rule := range rules {
+		parsed, err := postfix.ParseRule(rule[0], postfix.ParseOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		options[i] = parsed.Regex(postfix.Accept{
+			Value:  fmt.Sprint(i + 10),
+			Action: postfix.Action{Priority: i, Code: rule[1]}})
+	}
+	automata, numFinalSymbols, err := dfa.NewDFA(postfix.Alt{Options: options}, dfa.DIRECT_CONSTRUCTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfa.RemoveAbsortionStates(automata, numFinalSymbols)
+	data, err := dfa.EncodeBinary(automata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func tokens(t *testing.T, scanner *Scanner) []string {
+	result := make([]string, 0)
+	for {
+		token, err := scanner.GetNextToken()
+		if err == io.EOF {
+			return result
+		} else if err != nil {
+			return append(result, err.Error())
+		}
+		result = append(result, fmt.Sprintf("%d %s %q %d", token.Rule, token.Name, token.String(), token.Offset))
+	}
+}
+
+func TestScannerReturnsRules(t *testing.T) {
+	scanner, err := Load(automatonOf(t, [][2]string{
+		{"if", "{ return IF }"},
+		{"[a-z]+", "{ return ID; }"},
+		{"[0-9]+", "{ count++\n return NUMBER }"},
+		{"[ \n]+", "{}"},
+		{"\t", "{ return SKIP_LEXEME }"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRules := []Rule{
+		{Name: "IF", Action: "{ return IF }"},
+		{Name: "ID", Action: "{ return ID; }"},
+		{Action: "{ count++\n return NUMBER }"},
+		{Action: "{}", Skip: true},
+		{Name: "SKIP_LEXEME", Action: "{ return SKIP_LEXEME }", Skip: true},
+	}
+	if !slices.Equal(scanner.Rules(), expectedRules) {
+		t.Errorf("rules %v, expected %v", scanner.Rules(), expectedRules)
+	}
+
+	// The scanner can be reused with other input
+	for range 2 {
+		scanner.Reset(strings.NewReader("if iff\t42\nx"))
+		expected := []string{`0 IF "if" 0`, `1 ID "iff" 3`, `2  "42" 7`, `1 ID "x" 10`}
+		if got := tokens(t, scanner); !slices.Equal(got, expected) {
+			t.Errorf("expected %v\ngot %v", expected, got)
+		}
+	}
+
+	scanner.Reset(strings.NewReader("ab\n?"))
+	if got := tokens(t, scanner); len(got) != 2 || !strings.HasPrefix(got[1], "error line 2 column 1") {
+		t.Errorf("expected the pattern not found at line 2, got %v", got)
+	}
+}
+
+func TestLoadRejectsInvalidAutomata(t *testing.T) {
+	if _, err := Load([]byte(`{"version": 99}`)); err == nil {
+		t.Errorf("loaded an automaton of an unknown version")
+	}
+	if _, err := Load([]byte("YLXA")); err == nil {
+		t.Errorf("loaded a truncated automaton")
+	}
+}
+
+// Rules that recognize the empty string only recognize longer lexemes, or the scanner
+// would return empty tokens at the same offset forever.
+func TestScannerDoesNotReturnEmptyLexemes(t *testing.T) {
+	scanner, err := Load(automatonOf(t, [][2]string{
+		{"a*", "{ return A }"},
+		{"b*/c", "{ return B }"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"", []string{}},
+		{"aa", []string{`0 A "aa" 0`}},
+		{"aab", []string{`0 A "aa" 0`, "error line 1 column 3"}},
+		{"bbc", []string{`1 B "bb" 0`, "error line 1 column 3"}},
+		{"c", []string{"error line 1 column 1"}},
+	}
+	for _, c := range cases {
+		scanner.Reset(strings.NewReader(c.input))
+		got := tokens(t, scanner)
+		if len(got) != len(c.expected) {
+			t.Errorf("on %q expected %v, got %v", c.input, c.expected, got)
+			continue
+		}
+		for i := range got {
+			if !strings.HasPrefix(got[i], c.expected[i]) {
+				t.Errorf("on %q expected %v, got %v", c.input, c.expected, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package yaalextest checks from tests that the lexers generated by yaalex are up to date,
+// so a change to a definition is not checked in without generating its lexer again:
+//
+//	//go:generate yaalex generate -o lexer.go calc.lex
+//
+//	func TestLexerIsUpToDate(t *testing.T) {
+//		yaalextest.CheckLexer(t, "lexer.go")
+//	}
+package yaalextest
+
+import (
+	"errors"
+	"testing"
+
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+)
+
+// Fails the test if the lexer at path is stale: its definition changed, it was generated
+// by another version of yaalex or it was edited. The definition is the one in its stamp.
+func CheckLexer(t testing.TB, path string) {
+	t.Helper()
+	var stale *generator.StaleError
+	if err := generator.Verify(path, ""); errors.As(err, &stale) {
+		t.Errorf("%v, run go generate", err)
+	} else if err != nil {
+		t.Errorf("verifying %s: %v", path, err)
+	}
+}
@@ -0,0 +1,48 @@
+package yaalextest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	generator "github.com/DanielRasho/Lexer/internal/Generator"
+)
+
+// Records if the test failed instead of failing.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+}
+
+func TestCheckLexer(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "calc.lex")
+	lexer := filepath.Join(dir, "lexer.go")
+	if err := os.WriteFile(spec, []byte("%{\n%}\n{\n}\n%%\n[0-9]+  { return 1 }\n%%\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := generator.Compile(spec, lexer, generator.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := &recorder{TB: t}
+	CheckLexer(fresh, lexer)
+	if fresh.failed {
+		t.Errorf("the lexer was generated from the definition, but it is stale")
+	}
+
+	if err := os.WriteFile(spec, []byte("%{\n%}\n{\n}\n%%\n[0-9]*  { return 1 }\n%%\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := &recorder{TB: t}
+	CheckLexer(stale, lexer)
+	if !stale.failed {
+		t.Errorf("the definition changed, but the lexer is up to date")
+	}
+}